@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/rest/client"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/jasper"
+	"github.com/pkg/errors"
+)
+
+// agentSleepInterval is how long the agent waits between polls for a next
+// task when the API reports none is available.
+const agentSleepInterval = 10 * time.Second
+
+// Options contains startup options for the agent.
+type Options struct {
+	// HostID is the ID of the host the agent is running on.
+	HostID string
+	// StatusPort is the port the agent's local HTTP status server listens
+	// on.
+	StatusPort int
+	// LeaseRenewalInterval is how often the agent asks the server to
+	// extend its lease on the task it's currently running. Defaults to
+	// defaultLeaseRenewalInterval.
+	LeaseRenewalInterval time.Duration
+}
+
+func (o Options) leaseRenewalInterval() time.Duration {
+	if o.LeaseRenewalInterval <= 0 {
+		return defaultLeaseRenewalInterval
+	}
+	return o.LeaseRenewalInterval
+}
+
+// Agent manages the data necessary to run tasks on a host and exposes a
+// local HTTP status server for host-side health checking and diagnostics.
+type Agent struct {
+	opts       Options
+	comm       client.Communicator
+	tracker    jasper.OOMTracker
+	metrics    *agentMetrics
+	lease      leaseState
+	commands   *commandBookkeeping
+	oomHistory *oomHistory
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// New creates a new agent that reports host/status information under opts
+// and fetches/runs tasks using comm.
+func New(opts Options, comm client.Communicator) (*Agent, error) {
+	if comm == nil {
+		return nil, errors.New("communicator cannot be nil")
+	}
+
+	return &Agent{
+		opts:       opts,
+		comm:       comm,
+		tracker:    jasper.NewOOMTracker(),
+		metrics:    newAgentMetrics(),
+		commands:   newCommandBookkeeping(),
+		oomHistory: newOOMHistory(oomHistorySize),
+	}, nil
+}
+
+// Start starts the agent's status server and runs the task loop until ctx
+// is canceled. It returns an error immediately if another agent already
+// owns the status port.
+func (a *Agent) Start(ctx context.Context) error {
+	if err := a.startStatusServer(ctx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(a.loop(ctx))
+}
+
+// loop repeatedly asks the communicator for the next task to run until ctx
+// is canceled or getting the next task fails. When no task is available it
+// waits agentSleepInterval and asks again.
+func (a *Agent) loop(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		task, err := a.comm.GetNextTask(ctx)
+		if err != nil {
+			grip.Error(errors.Wrap(err, "problem getting next task"))
+			return errors.WithStack(err)
+		}
+		if task == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(agentSleepInterval):
+				continue
+			}
+		}
+
+		a.runTaskWithLeaseRenewal(ctx, task.TaskId)
+	}
+}
+
+// runTaskWithLeaseRenewal runs taskID's work (currently a no-op placeholder
+// in this package) while a background goroutine periodically extends the
+// task's lease with the server, so the server can tell the difference
+// between "still working" and "agent died" without relying solely on task
+// heartbeats. It records the agent's task-level metrics (start, duration,
+// and success/failure, the last inferred from whether the task's context
+// was canceled, either by the lease renewal goroutine aborting the task or
+// by ctx itself) around that call.
+func (a *Agent) runTaskWithLeaseRenewal(ctx context.Context, taskID string) {
+	taskCtx, abortTask := context.WithCancel(ctx)
+	defer abortTask()
+
+	leaseDone := make(chan struct{})
+	go func() {
+		defer close(leaseDone)
+		a.renewTaskLease(taskCtx, taskID, abortTask)
+	}()
+
+	a.metrics.tasksStarted.Inc()
+	start := time.Now()
+
+	a.runTask(taskCtx, taskID)
+
+	a.metrics.taskDuration.Observe(time.Since(start).Seconds())
+	if taskCtx.Err() != nil {
+		a.metrics.tasksFailed.Inc()
+	} else {
+		a.metrics.tasksSucceeded.Inc()
+	}
+
+	abortTask()
+	<-leaseDone
+}
+
+// runTask executes the work for a single task. The actual command-running
+// pipeline lives elsewhere in the agent; this is the seam the lease
+// renewal loop hooks into.
+func (a *Agent) runTask(ctx context.Context, taskID string) {
+	select {
+	case <-ctx.Done():
+	default:
+	}
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// cgroupMemoryStats is the pair of cgroup v2 memory figures that matter for
+// an OOM post-mortem: the configured ceiling and the high-water mark that
+// was reached before the kernel stepped in.
+type cgroupMemoryStats struct {
+	maxBytes       int64
+	highWaterBytes int64
+}
+
+// readCgroupMemoryStats reads the cgroup v2 memory.max and the memory
+// high-water mark (see readCgroupMemoryHighWater) for the cgroup that pid
+// belongs to. It only supports
+// cgroup v2, which is what the agent's distros run; on anything else it
+// returns an error and the caller simply leaves the fields blank.
+func readCgroupMemoryStats(pid int) (cgroupMemoryStats, error) {
+	cgroupPath, err := cgroupPathForPID(pid)
+	if err != nil {
+		return cgroupMemoryStats{}, errors.Wrapf(err, "problem finding cgroup for pid %d", pid)
+	}
+
+	maxBytes, err := readCgroupMemoryMax(cgroupPath)
+	if err != nil {
+		return cgroupMemoryStats{}, errors.Wrapf(err, "problem reading memory.max for pid %d", pid)
+	}
+
+	highWaterBytes, err := readCgroupMemoryHighWater(cgroupPath)
+	if err != nil {
+		return cgroupMemoryStats{}, errors.Wrapf(err, "problem reading cgroup memory high-water mark for pid %d", pid)
+	}
+
+	return cgroupMemoryStats{maxBytes: maxBytes, highWaterBytes: highWaterBytes}, nil
+}
+
+// cgroupPathForPID returns the absolute cgroup v2 directory (under
+// /sys/fs/cgroup) that pid belongs to, parsed out of /proc/<pid>/cgroup.
+func cgroupPathForPID(pid int) (string, error) {
+	data, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/cgroup")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// cgroup v2 lines look like "0::/path/to/cgroup".
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) == 3 && parts[0] == "0" && parts[1] == "" {
+			return "/sys/fs/cgroup" + parts[2], nil
+		}
+	}
+
+	return "", errors.New("no cgroup v2 entry found")
+}
+
+func readCgroupMemoryMax(cgroupPath string) (int64, error) {
+	data, err := ioutil.ReadFile(cgroupPath + "/memory.max")
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+
+	bytes, err := strconv.ParseInt(value, 10, 64)
+	return bytes, errors.WithStack(err)
+}
+
+// readCgroupMemoryHighWater returns the peak memory usage the cgroup ever
+// recorded (memory.peak, present on kernels 5.19+). Older kernels don't
+// expose a true high-water mark, so we fall back to the current usage,
+// which at least reflects usage at/around the time of the kill.
+func readCgroupMemoryHighWater(cgroupPath string) (int64, error) {
+	if data, err := ioutil.ReadFile(cgroupPath + "/memory.peak"); err == nil {
+		bytes, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		return bytes, errors.WithStack(err)
+	}
+
+	data, err := ioutil.ReadFile(cgroupPath + "/memory.current")
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	bytes, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return bytes, errors.WithStack(err)
+}
@@ -0,0 +1,18 @@
+// +build !linux
+
+package agent
+
+import "github.com/pkg/errors"
+
+// cgroupMemoryStats mirrors the Linux-only definition so buildOOMReport can
+// stay platform-agnostic.
+type cgroupMemoryStats struct {
+	maxBytes       int64
+	highWaterBytes int64
+}
+
+// readCgroupMemoryStats is only meaningful on Linux; elsewhere it always
+// errors so callers leave the cgroup fields of an OOMEntry blank.
+func readCgroupMemoryStats(pid int) (cgroupMemoryStats, error) {
+	return cgroupMemoryStats{}, errors.New("cgroup memory accounting is only supported on linux")
+}
@@ -0,0 +1,66 @@
+package agent
+
+import "sync"
+
+// commandBookkeeping tracks which shell.exec command block spawned each
+// still-running subprocess, along with its highest observed RSS, so that a
+// PID surfaced later by jasper.OOMTracker can be tied back to the task step
+// that launched it.
+type commandBookkeeping struct {
+	mu    sync.Mutex
+	procs map[int]*trackedProcess
+}
+
+type trackedProcess struct {
+	commandBlock string
+	peakRSSBytes int64
+}
+
+func newCommandBookkeeping() *commandBookkeeping {
+	return &commandBookkeeping{procs: map[int]*trackedProcess{}}
+}
+
+// trackPID records that pid was spawned by commandBlock (e.g.
+// "shell.exec" or a function/command display name).
+func (c *commandBookkeeping) trackPID(pid int, commandBlock string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.procs[pid] = &trackedProcess{commandBlock: commandBlock}
+}
+
+// observeRSS updates the peak RSS recorded for pid, if rssBytes is higher
+// than anything seen for it so far. It's a no-op for PIDs that were never
+// tracked, since the agent only polls RSS for processes it launched.
+func (c *commandBookkeeping) observeRSS(pid int, rssBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	proc, ok := c.procs[pid]
+	if !ok {
+		return
+	}
+	if rssBytes > proc.peakRSSBytes {
+		proc.peakRSSBytes = rssBytes
+	}
+}
+
+// forget drops bookkeeping for pid once its process has exited normally.
+func (c *commandBookkeeping) forget(pid int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.procs, pid)
+}
+
+// lookup returns the command block and peak RSS recorded for pid, if any.
+func (c *commandBookkeeping) lookup(pid int) (commandBlock string, peakRSSBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	proc, ok := c.procs[pid]
+	if !ok {
+		return "", 0
+	}
+	return proc.commandBlock, proc.peakRSSBytes
+}
@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// findOOMDmesgLine scans dmesg for the kernel's own record of pid being
+// OOM-killed, e.g. "Out of memory: Killed process 1234 (myprogram)". It
+// returns the empty string if dmesg isn't available or no matching line is
+// found, since this is best-effort enrichment, not something a report
+// should fail over.
+func findOOMDmesgLine(pid int) string {
+	out, err := exec.Command("dmesg").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+
+	needle := fmt.Sprintf("Killed process %s ", strconv.Itoa(pid))
+	lines := strings.Split(string(out), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.Contains(lines[i], needle) {
+			return strings.TrimSpace(lines[i])
+		}
+	}
+
+	return ""
+}
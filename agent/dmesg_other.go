@@ -0,0 +1,9 @@
+// +build !linux
+
+package agent
+
+// findOOMDmesgLine has no dmesg/journald equivalent wired up outside Linux,
+// so it's always empty.
+func findOOMDmesgLine(pid int) string {
+	return ""
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/message"
+)
+
+const (
+	// defaultLeaseRenewalInterval is how often the agent asks the server
+	// to extend its lease on the task it's currently running.
+	defaultLeaseRenewalInterval = time.Minute
+
+	// maxLeaseFailuresBeforeAbort is how many consecutive lease extension
+	// failures the agent tolerates before giving up on the current task,
+	// on the assumption that the server (or the network path to it) is
+	// unreachable for good.
+	maxLeaseFailuresBeforeAbort = 3
+)
+
+// leaseState is the agent's view of its current task lease, exposed on
+// /status for host-side diagnostics.
+type leaseState struct {
+	mu             sync.Mutex
+	lastExtendedAt time.Time
+	failureCount   int
+	deadline       time.Time
+}
+
+func (s *leaseState) recordSuccess(deadline time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastExtendedAt = time.Now()
+	s.deadline = deadline
+	s.failureCount = 0
+}
+
+func (s *leaseState) recordFailure() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failureCount++
+	return s.failureCount
+}
+
+func (s *leaseState) snapshot() (lastExtendedAt time.Time, failureCount int, deadline time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastExtendedAt, s.failureCount, s.deadline
+}
+
+// renewTaskLease periodically calls comm.ExtendTaskLease for taskID until
+// ctx is canceled. On repeated failures, or if the server reports the lease
+// has been revoked outright, it calls abortTask to cancel the running task
+// group instead of letting the agent keep working on task the server has
+// already reassigned.
+func (a *Agent) renewTaskLease(ctx context.Context, taskID string, abortTask context.CancelFunc) {
+	interval := a.opts.leaseRenewalInterval()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		extension, err := a.comm.ExtendTaskLease(ctx, taskID)
+		if err != nil {
+			failures := a.lease.recordFailure()
+			a.metrics.heartbeatFails.Inc()
+			grip.Error(message.WrapError(err, message.Fields{
+				"message":       "problem extending task lease",
+				"task_id":       taskID,
+				"failure_count": failures,
+			}))
+
+			if failures >= maxLeaseFailuresBeforeAbort {
+				grip.Error(message.Fields{
+					"message": "aborting task after repeated lease extension failures",
+					"task_id": taskID,
+				})
+				abortTask()
+				return
+			}
+
+			interval = backoffLeaseInterval(a.opts.leaseRenewalInterval(), failures)
+			continue
+		}
+
+		if extension.Revoked {
+			grip.Error(message.Fields{
+				"message": "server revoked task lease, aborting task",
+				"task_id": taskID,
+			})
+			a.lease.recordFailure()
+			a.metrics.heartbeatFails.Inc()
+			abortTask()
+			return
+		}
+
+		a.lease.recordSuccess(extension.Deadline)
+		interval = a.opts.leaseRenewalInterval()
+	}
+}
+
+// backoffLeaseInterval doubles the base renewal interval for every
+// consecutive failure, capped at 8x, so transient blips don't hammer the
+// server but a prolonged outage is still retried at a bounded rate.
+func backoffLeaseInterval(base time.Duration, failures int) time.Duration {
+	const maxMultiplier = 8
+	multiplier := 1 << uint(failures)
+	if multiplier > maxMultiplier {
+		multiplier = maxMultiplier
+	}
+	return base * time.Duration(multiplier)
+}
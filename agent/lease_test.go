@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/rest/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestLeaseFailure = errors.New("lease extension failed")
+
+// leaseTestCommunicator wraps client.Mock and overrides ExtendTaskLease so
+// tests can script lease extension failures/revocations without a real API
+// server.
+type leaseTestCommunicator struct {
+	*client.Mock
+
+	mu           sync.Mutex
+	leaseErr     error
+	leaseRevoked bool
+	extendCalls  int
+}
+
+func (c *leaseTestCommunicator) ExtendTaskLease(ctx context.Context, taskID string) (*client.LeaseExtension, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.extendCalls++
+
+	if c.leaseErr != nil {
+		return nil, c.leaseErr
+	}
+
+	return &client.LeaseExtension{Deadline: time.Now().Add(time.Minute), Revoked: c.leaseRevoked}, nil
+}
+
+func (c *leaseTestCommunicator) calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.extendCalls
+}
+
+func newLeaseTestAgent(t *testing.T, comm *leaseTestCommunicator) *Agent {
+	a, err := New(Options{HostID: "host", LeaseRenewalInterval: 5 * time.Millisecond}, comm)
+	require.NoError(t, err)
+	return a
+}
+
+func TestRenewTaskLeaseAbortsAfterRepeatedFailures(t *testing.T) {
+	comm := &leaseTestCommunicator{Mock: client.NewMock("url"), leaseErr: errTestLeaseFailure}
+	a := newLeaseTestAgent(t, comm)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var aborted bool
+	abortTask := func() { aborted = true }
+
+	a.renewTaskLease(ctx, "task1", abortTask)
+
+	assert.True(t, aborted)
+	_, failureCount, _ := a.lease.snapshot()
+	assert.Equal(t, maxLeaseFailuresBeforeAbort, failureCount)
+	assert.Equal(t, maxLeaseFailuresBeforeAbort, comm.calls())
+}
+
+func TestRenewTaskLeaseAbortsOnRevocation(t *testing.T) {
+	comm := &leaseTestCommunicator{Mock: client.NewMock("url"), leaseRevoked: true}
+	a := newLeaseTestAgent(t, comm)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var aborted bool
+	abortTask := func() { aborted = true }
+
+	a.renewTaskLease(ctx, "task1", abortTask)
+
+	assert.True(t, aborted)
+	assert.Equal(t, 1, comm.calls())
+}
+
+func TestRenewTaskLeaseRecordsSuccess(t *testing.T) {
+	comm := &leaseTestCommunicator{Mock: client.NewMock("url")}
+	a := newLeaseTestAgent(t, comm)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	a.renewTaskLease(ctx, "task1", func() { t.Fatal("lease renewal should not abort on success") })
+
+	lastExtendedAt, failureCount, deadline := a.lease.snapshot()
+	assert.False(t, lastExtendedAt.IsZero())
+	assert.Zero(t, failureCount)
+	assert.True(t, deadline.After(time.Now()))
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mongodb/jasper"
+)
+
+// oomHistorySize is how many enriched OOM reports the agent keeps around
+// for post-mortem inspection via /jasper/v1/list/oom/history, so a report
+// survives the task teardown that would otherwise wipe out the process
+// tree it describes.
+const oomHistorySize = 20
+
+// OOMEntry is everything the agent could correlate about a single
+// OOM-killed process: which command block launched it, how much memory its
+// cgroup allowed/was using, the kernel's own account of the kill, and its
+// peak observed RSS.
+type OOMEntry struct {
+	PID                        int    `json:"pid"`
+	CommandBlock               string `json:"command_block,omitempty"`
+	CgroupMemoryMaxBytes       int64  `json:"cgroup_memory_max_bytes,omitempty"`
+	CgroupMemoryHighWaterBytes int64  `json:"cgroup_memory_high_water_bytes,omitempty"`
+	DmesgLine                  string `json:"dmesg_line,omitempty"`
+	PeakRSSBytes               int64  `json:"peak_rss_bytes,omitempty"`
+}
+
+// OOMReport is the enriched response served from /jasper/v1/list/oom (and
+// retained in the agent's OOM history ring buffer).
+type OOMReport struct {
+	GeneratedAt  time.Time  `json:"generated_at"`
+	WasOOMKilled bool       `json:"was_oom_killed"`
+	Entries      []OOMEntry `json:"entries,omitempty"`
+}
+
+// buildOOMReport correlates tracker's raw PID list against the agent's
+// command bookkeeping and the host's cgroup/dmesg state.
+func buildOOMReport(tracker jasper.OOMTracker, commands *commandBookkeeping) OOMReport {
+	wasOOMKilled, pids := tracker.Report()
+
+	report := OOMReport{
+		GeneratedAt:  time.Now(),
+		WasOOMKilled: wasOOMKilled,
+	}
+
+	for _, pid := range pids {
+		entry := OOMEntry{PID: pid}
+
+		if commands != nil {
+			entry.CommandBlock, entry.PeakRSSBytes = commands.lookup(pid)
+		}
+
+		if stats, err := readCgroupMemoryStats(pid); err == nil {
+			entry.CgroupMemoryMaxBytes = stats.maxBytes
+			entry.CgroupMemoryHighWaterBytes = stats.highWaterBytes
+		}
+
+		entry.DmesgLine = findOOMDmesgLine(pid)
+
+		report.Entries = append(report.Entries, entry)
+	}
+
+	return report
+}
+
+// oomHistory is a fixed-capacity ring buffer of the most recent OOM
+// reports, so a post-mortem can look back past the task that triggered the
+// kill even after its process tree is long gone.
+type oomHistory struct {
+	mu      sync.Mutex
+	reports []OOMReport
+	cap     int
+}
+
+func newOOMHistory(capacity int) *oomHistory {
+	return &oomHistory{cap: capacity}
+}
+
+func (h *oomHistory) add(report OOMReport) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.reports = append(h.reports, report)
+	if overflow := len(h.reports) - h.cap; overflow > 0 {
+		h.reports = h.reports[overflow:]
+	}
+}
+
+func (h *oomHistory) snapshot() []OOMReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make([]OOMReport, len(h.reports))
+	copy(out, h.reports)
+	return out
+}
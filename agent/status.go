@@ -0,0 +1,213 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/message"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// statusResponse is served from the agent's local /status endpoint and is
+// meant for host-side health checking: is the agent up, and what does it
+// see on the box it's running on.
+type statusResponse struct {
+	BuildId     string                 `json:"build_revision"`
+	AgentPid    int                    `json:"agent_pid"`
+	HostId      string                 `json:"host_id"`
+	SystemInfo  *message.SystemInfo    `json:"sys_info,omitempty"`
+	ProcessTree []*message.ProcessInfo `json:"processes,omitempty"`
+
+	// LastLeaseExtendedAt, LeaseFailureCount, and LeaseDeadline reflect
+	// the agent's most recent attempt to extend its lease on a task.
+	// They're zero-valued until the first lease extension attempt, and
+	// are not reset when a task finishes, so between tasks they still
+	// describe the last task's lease.
+	LastLeaseExtendedAt time.Time `json:"last_lease_extended_at,omitempty"`
+	LeaseFailureCount   int       `json:"lease_failure_count"`
+	LeaseDeadline       time.Time `json:"lease_deadline,omitempty"`
+}
+
+func buildResponse(opts Options) statusResponse {
+	resp := statusResponse{
+		BuildId:  evergreen.BuildRevision,
+		AgentPid: os.Getpid(),
+		HostId:   opts.HostID,
+	}
+
+	if sysInfo, ok := message.CollectSystemInfo().(*message.SystemInfo); ok {
+		resp.SystemInfo = sysInfo
+	}
+
+	for _, composer := range message.CollectProcessInfoSelfWithChildren() {
+		if procInfo, ok := composer.(*message.ProcessInfo); ok {
+			resp.ProcessTree = append(resp.ProcessTree, procInfo)
+		}
+	}
+
+	return resp
+}
+
+// agentMetrics holds the Prometheus collectors the agent updates as it
+// works through tasks. They're registered against a private registry
+// (rather than the global default) so multiple agents in the same process,
+// as in tests, don't collide on collector registration.
+type agentMetrics struct {
+	registry *prometheus.Registry
+
+	tasksStarted   prometheus.Counter
+	tasksSucceeded prometheus.Counter
+	tasksFailed    prometheus.Counter
+	taskDuration   prometheus.Histogram
+	heartbeatFails prometheus.Counter
+	oomDetections  prometheus.Counter
+	commandLatency *prometheus.HistogramVec
+}
+
+func newAgentMetrics() *agentMetrics {
+	m := &agentMetrics{registry: prometheus.NewRegistry()}
+
+	m.tasksStarted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "evergreen_agent_tasks_started_total",
+		Help: "Number of tasks the agent has started running.",
+	})
+	m.tasksSucceeded = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "evergreen_agent_tasks_succeeded_total",
+		Help: "Number of tasks the agent has completed successfully.",
+	})
+	m.tasksFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "evergreen_agent_tasks_failed_total",
+		Help: "Number of tasks the agent has completed with a failure.",
+	})
+	m.taskDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "evergreen_agent_task_duration_seconds",
+		Help:    "Wall-clock duration of completed tasks.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 15),
+	})
+	m.heartbeatFails = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "evergreen_agent_heartbeat_failures_total",
+		Help: "Number of task heartbeats that failed to reach the API server.",
+	})
+	m.oomDetections = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "evergreen_agent_oom_detections_total",
+		Help: "Number of OOM kills detected among task subprocesses.",
+	})
+	m.commandLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "evergreen_agent_command_duration_seconds",
+		Help:    "Execution latency of task commands, labeled by command type.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"command"})
+
+	m.registry.MustRegister(
+		m.tasksStarted,
+		m.tasksSucceeded,
+		m.tasksFailed,
+		m.taskDuration,
+		m.heartbeatFails,
+		m.oomDetections,
+		m.commandLatency,
+	)
+
+	return m
+}
+
+// observeCommandDuration records how long a single command took to run, to
+// surface per-command-type latency histograms on /metrics. Nothing calls
+// this yet: the agent package doesn't run task commands itself (see
+// runTask's doc comment), so there's no real call site to wire it into
+// until that pipeline lands here. It's defined now so the command-running
+// code can start calling it without a follow-up metrics change.
+func (m *agentMetrics) observeCommandDuration(commandType string, d time.Duration) {
+	m.commandLatency.WithLabelValues(commandType).Observe(d.Seconds())
+}
+
+// startStatusServer binds the agent's local status port and serves /status,
+// the Jasper OOM-tracker endpoints, Prometheus metrics, and pprof profiles
+// in the background until ctx is canceled. It returns an error immediately
+// if the port is already bound by another agent.
+func (a *Agent) startStatusServer(ctx context.Context) error {
+	addr := fmt.Sprintf("127.0.0.1:%d", a.opts.StatusPort)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Errorf("another agent is running on %d", a.opts.StatusPort)
+	}
+
+	a.mu.Lock()
+	a.listener = listener
+	a.mu.Unlock()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", a.statusHandler)
+	mux.HandleFunc("/jasper/v1/list/oom", a.oomHandler)
+	mux.HandleFunc("/jasper/v1/list/oom/history", a.oomHistoryHandler)
+	mux.Handle("/metrics", promhttp.HandlerFor(a.metrics.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	srv := &http.Server{Handler: mux}
+
+	go func() {
+		grip.Error(errors.Wrap(srv.Serve(listener), "status server exited"))
+	}()
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+
+	return nil
+}
+
+func (a *Agent) statusHandler(w http.ResponseWriter, r *http.Request) {
+	resp := buildResponse(a.opts)
+	resp.LastLeaseExtendedAt, resp.LeaseFailureCount, resp.LeaseDeadline = a.lease.snapshot()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	grip.Error(json.NewEncoder(w).Encode(resp))
+}
+
+// oomHandler checks for OOM kills among the task's subprocesses and
+// responds with an enriched OOMReport: each killed PID correlated against
+// the command block that launched it, its cgroup memory ceiling/high-water
+// mark, the kernel's dmesg line, and its peak observed RSS. The report is
+// also retained in the agent's OOM history ring buffer so it survives past
+// task teardown.
+func (a *Agent) oomHandler(w http.ResponseWriter, r *http.Request) {
+	if err := a.tracker.Check(); err != nil {
+		grip.Error(errors.Wrap(err, "problem checking for OOM kills"))
+	}
+
+	report := buildOOMReport(a.tracker, a.commands)
+	if report.WasOOMKilled {
+		a.metrics.oomDetections.Inc()
+	}
+	a.oomHistory.add(report)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	grip.Error(json.NewEncoder(w).Encode(report))
+}
+
+// oomHistoryHandler returns the agent's retained OOM reports, most recent
+// last, so a post-mortem can inspect kills from earlier tasks even after
+// their process trees are gone.
+func (a *Agent) oomHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	grip.Error(json.NewEncoder(w).Encode(a.oomHistory.snapshot()))
+}
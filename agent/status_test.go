@@ -2,8 +2,10 @@ package agent
 
 import (
 	"context"
+	"io/ioutil"
 	"net/http"
 	"os"
+	osexec "os/exec"
 	"runtime"
 	"strings"
 	"testing"
@@ -77,6 +79,11 @@ func (s *StatusSuite) TestAgentStartsStatusServer() {
 	resp, err := http.Get("http://127.0.0.1:2286/status")
 	s.Require().NoError(err)
 	s.Equal(200, resp.StatusCode)
+
+	var status statusResponse
+	s.Require().NoError(util.ReadJSONInto(resp.Body, &status))
+	s.True(status.LastLeaseExtendedAt.IsZero(), "no task is running, so no lease should have been extended yet")
+	s.Zero(status.LeaseFailureCount)
 }
 
 func (s *StatusSuite) TestAgentFailsToStartTwice() {
@@ -137,6 +144,74 @@ func (s *StatusSuite) TestAgentFailsToStartTwice() {
 	s.Require().NoError(err)
 }
 
+func (s *StatusSuite) TestMetricsEndpoint() {
+	agt, err := New(s.testOpts, client.NewMock("url"))
+	s.Require().NoError(err)
+	mockCommunicator := agt.comm.(*client.Mock)
+	mockCommunicator.NextTaskIsNil = true
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go func() {
+		_ = agt.Start(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get("http://127.0.0.1:2286/metrics")
+	s.Require().NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	s.Require().NoError(err)
+	s.Contains(string(body), "evergreen_agent_tasks_started_total")
+}
+
+// TestMetricsEndpointReflectsTaskActivity lets the agent's loop actually run
+// a task (rather than always getting a nil "no work" response, like every
+// other test in this suite) and checks that the task counters the loop is
+// supposed to drive are no longer stuck at zero.
+func (s *StatusSuite) TestMetricsEndpointReflectsTaskActivity() {
+	// Earlier tests leave an agent listening on 2286, so this agent gets a
+	// port of its own; otherwise it would fail to bind and the request
+	// below would read the other agent's metrics.
+	s.testOpts.StatusPort = 2288
+	agt, err := New(s.testOpts, client.NewMock("url"))
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go func() {
+		_ = agt.Start(ctx)
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	resp, err := http.Get("http://127.0.0.1:2288/metrics")
+	s.Require().NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	s.Require().NoError(err)
+
+	s.NotContains(string(body), "evergreen_agent_tasks_started_total 0\n", "the task loop should have started at least one task by now")
+	s.NotContains(string(body), "evergreen_agent_tasks_succeeded_total 0\n", "the placeholder runTask should have completed at least one task successfully")
+}
+
+func (s *StatusSuite) TestPprofEndpoint() {
+	agt, err := New(s.testOpts, client.NewMock("url"))
+	s.Require().NoError(err)
+	mockCommunicator := agt.comm.(*client.Mock)
+	mockCommunicator.NextTaskIsNil = true
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go func() {
+		_ = agt.Start(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get("http://127.0.0.1:2286/debug/pprof/")
+	s.Require().NoError(err)
+	s.Equal(200, resp.StatusCode)
+}
+
 func (s *StatusSuite) TestCheckOOMSucceeds() {
 	if runtime.GOOS == "darwin" {
 		s.T().Skip("OOM tests will not work on static mac hosts because logs are never cleared and will be too long to parse")
@@ -182,3 +257,77 @@ func (s *StatusSuite) TestCheckOOMSucceeds() {
 	s.False(wasOomKilled)
 	s.Len(pids, 0)
 }
+
+func (s *StatusSuite) TestOOMHistoryEndpoint() {
+	// Use a fresh port so the history read below belongs to this agent,
+	// not to one left running on 2286 by an earlier test.
+	s.testOpts.StatusPort = 2289
+	agt, err := New(s.testOpts, client.NewMock("url"))
+	s.Require().NoError(err)
+	mockCommunicator := agt.comm.(*client.Mock)
+	mockCommunicator.NextTaskIsNil = true
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go func() {
+		_ = agt.Start(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = http.Get("http://127.0.0.1:2289/jasper/v1/list/oom")
+	s.Require().NoError(err)
+
+	resp, err := http.Get("http://127.0.0.1:2289/jasper/v1/list/oom/history")
+	s.Require().NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	var history []OOMReport
+	s.Require().NoError(util.ReadJSONInto(resp.Body, &history))
+	s.Require().Len(history, 1, "the earlier check should have recorded a report")
+	s.False(history[0].WasOOMKilled)
+}
+
+// fakeOOMTracker lets tests drive buildOOMReport with a specific, already
+// "killed" PID without needing a real OOM event.
+type fakeOOMTracker struct {
+	pids []int
+}
+
+func (t *fakeOOMTracker) Check() error { return nil }
+
+func (t *fakeOOMTracker) Clear() error { return nil }
+
+func (t *fakeOOMTracker) Report() (bool, []int) { return len(t.pids) > 0, t.pids }
+
+// TestOOMReportEnrichment exercises buildOOMReport end-to-end on Linux: it
+// launches a real subprocess through the standard library (standing in for
+// the agent's command-execution pipeline), records it in the command
+// bookkeeping the way the real pipeline would, then asserts that a report
+// built against that PID is enriched with the command block it was tracked
+// under and its recorded peak RSS. The cgroup and dmesg fields are not
+// checked, since this particular process was never actually OOM-killed.
+func (s *StatusSuite) TestOOMReportEnrichment() {
+	if runtime.GOOS != "linux" {
+		s.T().Skip("cgroup and dmesg correlation are only implemented on linux")
+	}
+
+	cmd := osexec.Command("sleep", "30")
+	s.Require().NoError(cmd.Start())
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	commands := newCommandBookkeeping()
+	commands.trackPID(cmd.Process.Pid, "shell.exec: sleep 30")
+	commands.observeRSS(cmd.Process.Pid, 1024*1024)
+
+	tracker := &fakeOOMTracker{pids: []int{cmd.Process.Pid}}
+	report := buildOOMReport(tracker, commands)
+
+	s.True(report.WasOOMKilled)
+	s.Require().Len(report.Entries, 1)
+	entry := report.Entries[0]
+	s.Equal(cmd.Process.Pid, entry.PID)
+	s.Equal("shell.exec: sleep 30", entry.CommandBlock)
+	s.Equal(int64(1024*1024), entry.PeakRSSBytes)
+}
@@ -0,0 +1,93 @@
+package commitqueue
+
+import (
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/message"
+	"github.com/pkg/errors"
+	mgobson "gopkg.in/mgo.v2/bson"
+)
+
+// NextBatch returns up to n items from the front of the queue as a
+// contiguous batch, to be speculatively tested together as a single
+// combined patch (a "merge train") instead of one at a time. The batch is
+// capped at cq.BatchSize when it's set, even if the caller asks for more.
+func (cq *CommitQueue) NextBatch(n int) []CommitQueueItem {
+	if cq.BatchSize > 0 && n > cq.BatchSize {
+		n = cq.BatchSize
+	}
+	if n > len(cq.Queue) {
+		n = len(cq.Queue)
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	batch := make([]CommitQueueItem, n)
+	copy(batch, cq.Queue[:n])
+	return batch
+}
+
+// BisectFailedBatch reconciles a merge train batch that failed testing as a
+// whole. Every item ahead of failingIssue is assumed good and is dequeued
+// (merged); failingIssue itself is ejected from the queue entirely; every
+// item after it is re-enqueued at the front of the queue so it gets
+// retested, either individually or as part of a smaller batch.
+func BisectFailedBatch(projectID string, batch []CommitQueueItem, failingIssue string) error {
+	failIdx := -1
+	for i, item := range batch {
+		if item.Issue == failingIssue {
+			failIdx = i
+			break
+		}
+	}
+	if failIdx == -1 {
+		return errors.Errorf("issue '%s' is not part of the given batch", failingIssue)
+	}
+
+	for _, item := range batch[:failIdx] {
+		if err := dequeueFromCommitQueue(projectID, item.Issue); err != nil {
+			return errors.Wrapf(err, "problem dequeuing merged item '%s'", item.Issue)
+		}
+	}
+
+	if err := dequeueFromCommitQueue(projectID, failingIssue); err != nil {
+		return errors.Wrapf(err, "problem ejecting failing item '%s'", failingIssue)
+	}
+	grip.Info(message.Fields{
+		"message":    "ejected item from commit queue after it failed a batch",
+		"project_id": projectID,
+		"item":       failingIssue,
+	})
+
+	// The tail is still sitting in the persisted queue behind where the
+	// batch started (NextBatch only ever copies, it doesn't remove), so it
+	// has to be dequeued before being pushed back to the front; otherwise
+	// every item after the failing one ends up duplicated in the queue.
+	tail := batch[failIdx+1:]
+	for _, item := range tail {
+		if err := dequeueFromCommitQueue(projectID, item.Issue); err != nil {
+			return errors.Wrapf(err, "problem dequeuing batch tail item '%s' before requeuing", item.Issue)
+		}
+	}
+
+	if err := requeueItems(projectID, tail); err != nil {
+		return errors.Wrap(err, "problem re-enqueuing batch tail")
+	}
+
+	return nil
+}
+
+// requeueItems pushes items back onto the front of the queue, preserving
+// their relative order.
+func requeueItems(projectID string, items []CommitQueueItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	return errors.WithStack(db.Update(
+		Collection,
+		mgobson.M{"_id": projectID},
+		mgobson.M{"$push": mgobson.M{"queue": mgobson.M{"$each": items, "$position": 0}}},
+	))
+}
@@ -0,0 +1,114 @@
+package commitqueue
+
+import (
+	"context"
+
+	"github.com/evergreen-ci/evergreen/model/event"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+)
+
+// EventType identifies the kind of state transition a commit queue item has
+// gone through.
+type EventType string
+
+const (
+	ItemEnqueued       EventType = "item_enqueued"
+	ItemStartedTesting EventType = "item_started_testing"
+	ItemBlocked        EventType = "item_blocked"
+	ItemDequeued       EventType = "item_dequeued"
+	MergeSucceeded     EventType = "merge_succeeded"
+	MergeFailed        EventType = "merge_failed"
+)
+
+// Event describes a single commit queue state transition. Not every field
+// is populated for every EventType: Status is only meaningful for the merge
+// events, for example. PRs is read for every event type by sinks that
+// report per PR, such as the GitHub Checks sink.
+type Event struct {
+	Type      EventType
+	ProjectID string
+	Item      string
+	PRs       []event.PRInfo
+	Status    string
+	Reason    string
+
+	// BatchID and BatchOutcome are set when Item was tested as part of a
+	// merge train batch rather than on its own.
+	BatchID      string
+	BatchOutcome string
+}
+
+// Sink receives commit queue events. Implementations should treat event
+// types they don't care about as a no-op rather than an error, since new
+// event types may be added over time.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, e Event) error
+}
+
+// EventBus fans a single commit queue event out to every registered sink.
+// It replaces the old pattern of the GithubPRLogger being the only thing
+// that ever heard about a merge result.
+type EventBus struct {
+	sinks []Sink
+}
+
+// NewEventBus returns an EventBus that dispatches to sinks in the order
+// given.
+func NewEventBus(sinks ...Sink) *EventBus {
+	return &EventBus{sinks: sinks}
+}
+
+// Publish sends e to every registered sink, logging (but not stopping on)
+// individual sink failures so one bad webhook endpoint can't keep the
+// others from hearing about an event.
+func (b *EventBus) Publish(ctx context.Context, e Event) error {
+	catcher := grip.NewBasicCatcher()
+	for _, sink := range b.sinks {
+		if err := sink.Send(ctx, e); err != nil {
+			catcher.Add(err)
+			grip.Error(message.WrapError(err, message.Fields{
+				"message":    "commit queue sink failed to handle event",
+				"sink":       sink.Name(),
+				"event_type": e.Type,
+				"project_id": e.ProjectID,
+				"item":       e.Item,
+			}))
+		}
+	}
+	return catcher.Resolve()
+}
+
+// githubPRCommentSink adapts the existing GithubPRLogger grip sender (which
+// only understands merge success/failure) into a Sink, so it can live
+// alongside the newer sinks on the same EventBus.
+type githubPRCommentSink struct {
+	logger interface{ Send(message.Composer) }
+}
+
+// NewGithubPRCommentSink wraps an existing GithubPRLogger sender (from
+// NewGithubPRLogger or NewMockGithubPRLogger) as an EventBus Sink.
+func NewGithubPRCommentSink(logger interface{ Send(message.Composer) }) Sink {
+	return &githubPRCommentSink{logger: logger}
+}
+
+func (s *githubPRCommentSink) Name() string { return "github-pr-comment" }
+
+func (s *githubPRCommentSink) Send(ctx context.Context, e Event) error {
+	if e.Type != MergeSucceeded && e.Type != MergeFailed {
+		return nil
+	}
+
+	s.logger.Send(NewGithubMergePRMessage(level.Info, GithubMergePR{
+		Status:       e.Status,
+		ProjectID:    e.ProjectID,
+		Item:         e.Item,
+		PRs:          e.PRs,
+		BatchID:      e.BatchID,
+		BatchOutcome: e.BatchOutcome,
+	}))
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package commitqueue
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink is an in-memory test Sink that just records every event it
+// receives, so tests can assert on dispatch order/content without standing
+// up a real GitHub client or HTTP server.
+type memorySink struct {
+	name   string
+	events []Event
+}
+
+func (s *memorySink) Name() string { return s.name }
+
+func (s *memorySink) Send(ctx context.Context, e Event) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func TestEventBusDispatchesToAllSinks(t *testing.T) {
+	first := &memorySink{name: "first"}
+	second := &memorySink{name: "second"}
+	bus := NewEventBus(first, second)
+
+	e := Event{Type: ItemEnqueued, ProjectID: "mci", Item: "1"}
+	require.NoError(t, bus.Publish(context.Background(), e))
+
+	assert.Equal(t, []Event{e}, first.events)
+	assert.Equal(t, []Event{e}, second.events)
+}
+
+func TestEventBusContinuesAfterSinkFailure(t *testing.T) {
+	failing := &failingSink{}
+	succeeding := &memorySink{name: "succeeding"}
+	bus := NewEventBus(failing, succeeding)
+
+	err := bus.Publish(context.Background(), Event{Type: ItemDequeued, ProjectID: "mci", Item: "1"})
+	assert.Error(t, err)
+	assert.Len(t, succeeding.events, 1, "a failing sink should not block dispatch to the remaining sinks")
+}
+
+type failingSink struct{}
+
+func (s *failingSink) Name() string { return "failing" }
+func (s *failingSink) Send(ctx context.Context, e Event) error {
+	return errors.New("sink is down")
+}
+
+func TestEventBusMergeSucceededDequeuesItemThroughGithubPRCommentSink(t *testing.T) {
+	require.NoError(t, db.ClearCollections(Collection))
+	cq := &CommitQueue{
+		ProjectID: "mci",
+		Queue: []CommitQueueItem{
+			{Issue: "1"},
+			{Issue: "2"},
+		},
+	}
+	require.NoError(t, InsertQueue(cq))
+
+	errLogger := &mockErrorLogger{}
+	ghPRLogger, err := NewMockGithubPRLogger("mock_gh_pr_logger", errLogger)
+	require.NoError(t, err)
+
+	bus := NewEventBus(NewGithubPRCommentSink(ghPRLogger))
+
+	err = bus.Publish(context.Background(), Event{
+		Type:      MergeSucceeded,
+		Status:    evergreen.PatchSucceeded,
+		ProjectID: "mci",
+		Item:      "1",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, errLogger.errList)
+
+	updated, err := FindOneId("mci")
+	require.NoError(t, err)
+	assert.Equal(t, "2", updated.Next().Issue)
+}
+
+func TestWebhookSinkSignsRequestBody(t *testing.T) {
+	secret := []byte("shared-secret")
+	var gotSignature, gotBody string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-Evergreen-Signature")
+		body := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, secret)
+	e := Event{Type: MergeFailed, ProjectID: "mci", Item: "1", Reason: "merge conflict"}
+	require.NoError(t, sink.Send(context.Background(), e))
+
+	expectedBody, err := json.Marshal(e)
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedBody), gotBody)
+
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(expectedBody)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), gotSignature)
+}
@@ -0,0 +1,117 @@
+package commitqueue
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// githubChecksSink reports commit queue progress on each PR's GitHub Checks
+// tab, rather than (or in addition to) a plain PR comment. It creates one
+// check run per PR the first time it hears about it, then updates that same
+// check run as the item moves through the queue, instead of creating a new
+// one on every event.
+type githubChecksSink struct {
+	token string
+	// baseURL overrides the GitHub API base URL; only ever set by tests,
+	// which point it at a local httptest server.
+	baseURL string
+
+	mu          sync.Mutex
+	checkRunIDs map[string]int64
+}
+
+// NewGithubChecksSink returns a Sink that creates/updates a GitHub check run
+// on every PR in a commit queue item as it moves through the queue.
+func NewGithubChecksSink(token string) Sink {
+	return &githubChecksSink{token: token, checkRunIDs: map[string]int64{}}
+}
+
+func (s *githubChecksSink) Name() string { return "github-checks" }
+
+func (s *githubChecksSink) Send(ctx context.Context, e Event) error {
+	client := github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: s.token})))
+	if s.baseURL != "" {
+		u, err := url.Parse(s.baseURL)
+		if err != nil {
+			return errors.Wrap(err, "problem parsing override base URL")
+		}
+		client.BaseURL = u
+	}
+
+	status, conclusion := checkRunStatus(e.Type)
+
+	for _, pr := range e.PRs {
+		key := checkRunKey(pr.Owner, pr.Repo, pr.PRNum)
+
+		s.mu.Lock()
+		runID, tracked := s.checkRunIDs[key]
+		s.mu.Unlock()
+
+		if !tracked {
+			opts := github.CreateCheckRunOptions{
+				Name:    "evergreen-commit-queue",
+				HeadSHA: pr.Ref,
+				Status:  &status,
+			}
+			if conclusion != "" {
+				opts.Conclusion = &conclusion
+			}
+
+			run, _, err := client.Checks.CreateCheckRun(ctx, pr.Owner, pr.Repo, opts)
+			if err != nil {
+				return errors.Wrapf(err, "problem creating check run for '%s/%s'#%d", pr.Owner, pr.Repo, pr.PRNum)
+			}
+
+			s.mu.Lock()
+			s.checkRunIDs[key] = run.GetID()
+			s.mu.Unlock()
+			continue
+		}
+
+		opts := github.UpdateCheckRunOptions{
+			Name:   "evergreen-commit-queue",
+			Status: &status,
+		}
+		if conclusion != "" {
+			opts.Conclusion = &conclusion
+		}
+
+		if _, _, err := client.Checks.UpdateCheckRun(ctx, pr.Owner, pr.Repo, runID, opts); err != nil {
+			return errors.Wrapf(err, "problem updating check run for '%s/%s'#%d", pr.Owner, pr.Repo, pr.PRNum)
+		}
+	}
+
+	return nil
+}
+
+// checkRunKey identifies the check run tracked for a single PR, independent
+// of which commit queue event triggered the update.
+func checkRunKey(owner, repo string, prNum int) string {
+	return fmt.Sprintf("%s/%s#%d", owner, repo, prNum)
+}
+
+// checkRunStatus maps a commit queue EventType onto the GitHub Checks API's
+// status/conclusion vocabulary. conclusion is empty for in-progress states,
+// since the Checks API only accepts a conclusion once status is "completed".
+func checkRunStatus(t EventType) (status string, conclusion string) {
+	switch t {
+	case ItemEnqueued, ItemBlocked:
+		return "queued", ""
+	case ItemStartedTesting:
+		return "in_progress", ""
+	case MergeSucceeded:
+		return "completed", "success"
+	case MergeFailed:
+		return "completed", "failure"
+	case ItemDequeued:
+		return "completed", "neutral"
+	default:
+		return "queued", ""
+	}
+}
@@ -0,0 +1,99 @@
+package commitqueue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen/model/event"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type GithubChecksSinkSuite struct {
+	suite.Suite
+	server      *httptest.Server
+	creates     int
+	updates     int
+	lastStatus  string
+	lastRunPath string
+}
+
+func TestGithubChecksSinkSuite(t *testing.T) {
+	suite.Run(t, new(GithubChecksSinkSuite))
+}
+
+func (s *GithubChecksSinkSuite) SetupTest() {
+	s.creates = 0
+	s.updates = 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/evergreen-ci/evergreen/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		s.Require().Equal(http.MethodPost, r.Method)
+		s.creates++
+
+		var body struct {
+			Status *string `json:"status"`
+		}
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		if body.Status != nil {
+			s.lastStatus = *body.Status
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 42})
+	})
+	mux.HandleFunc("/repos/evergreen-ci/evergreen/check-runs/42", func(w http.ResponseWriter, r *http.Request) {
+		s.Require().Equal(http.MethodPatch, r.Method)
+		s.updates++
+
+		var body struct {
+			Status *string `json:"status"`
+		}
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		if body.Status != nil {
+			s.lastStatus = *body.Status
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 42})
+	})
+
+	s.server = httptest.NewServer(mux)
+}
+
+func (s *GithubChecksSinkSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *GithubChecksSinkSuite) TestCreatesOnceThenUpdates() {
+	sink := &githubChecksSink{token: "token", baseURL: s.server.URL + "/", checkRunIDs: map[string]int64{}}
+
+	pr := event.PRInfo{Owner: "evergreen-ci", Repo: "evergreen", Ref: "deadbeef", PRNum: 1}
+
+	s.Require().NoError(sink.Send(context.Background(), Event{
+		Type: ItemStartedTesting,
+		PRs:  []event.PRInfo{pr},
+	}))
+	s.Equal(1, s.creates)
+	s.Equal(0, s.updates)
+	s.Equal("in_progress", s.lastStatus)
+
+	s.Require().NoError(sink.Send(context.Background(), Event{
+		Type:   MergeSucceeded,
+		Status: "succeeded",
+		PRs:    []event.PRInfo{pr},
+	}))
+	s.Equal(1, s.creates, "the second event for the same PR should update the existing check run, not create a new one")
+	s.Equal(1, s.updates)
+	s.Equal("completed", s.lastStatus)
+
+	s.Require().Contains(sink.checkRunIDs, checkRunKey(pr.Owner, pr.Repo, pr.PRNum))
+}
+
+func TestCheckRunKeyIsStablePerPR(t *testing.T) {
+	require.Equal(t, checkRunKey("evergreen-ci", "evergreen", 1), checkRunKey("evergreen-ci", "evergreen", 1))
+	require.NotEqual(t, checkRunKey("evergreen-ci", "evergreen", 1), checkRunKey("evergreen-ci", "evergreen", 2))
+}
@@ -0,0 +1,20 @@
+package commitqueue
+
+import (
+	"context"
+
+	"github.com/evergreen-ci/evergreen/model/event"
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// mergeGithubPR merges a single PR that's part of a commit queue item,
+// authenticating with token.
+func mergeGithubPR(token string, pr event.PRInfo) error {
+	ctx := context.Background()
+	client := github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+
+	_, _, err := client.PullRequests.Merge(ctx, pr.Owner, pr.Repo, pr.PRNum, pr.CommitTitle, nil)
+	return errors.WithStack(err)
+}
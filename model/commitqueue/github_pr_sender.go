@@ -0,0 +1,143 @@
+package commitqueue
+
+import (
+	"fmt"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/event"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/send"
+	"github.com/pkg/errors"
+)
+
+// GithubMergePR carries the outcome of a single commit queue item's merge
+// attempt: whether it succeeded or failed, which project/item it belongs
+// to, and the PRs that made up the merge. BatchID and BatchOutcome are only
+// populated when the item was tested as part of a merge train batch (see
+// CommitQueue.NextBatch); BatchOutcome distinguishes "merged as part of the
+// batch" from "ejected: caused the batch to fail".
+type GithubMergePR struct {
+	Status       string
+	ProjectID    string
+	Item         string
+	PRs          []event.PRInfo
+	BatchID      string
+	BatchOutcome string
+}
+
+type githubMergePRMessage struct {
+	*message.Base
+	raw GithubMergePR
+}
+
+// NewGithubMergePRMessage wraps msg as a grip message.Composer so it can be
+// logged through a GithubPRLogger sender (or any other grip sender).
+func NewGithubMergePRMessage(priority level.Priority, msg GithubMergePR) message.Composer {
+	c := &githubMergePRMessage{
+		Base: message.NewBase(""),
+		raw:  msg,
+	}
+	_ = c.SetPriority(priority)
+	return c
+}
+
+func (c *githubMergePRMessage) Loggable() bool {
+	return c.raw.ProjectID != "" && c.raw.Item != ""
+}
+
+func (c *githubMergePRMessage) String() string {
+	if c.raw.BatchID == "" {
+		return fmt.Sprintf("commit queue item '%s' in project '%s': %s", c.raw.Item, c.raw.ProjectID, c.raw.Status)
+	}
+	return fmt.Sprintf("commit queue item '%s' in project '%s': %s (batch '%s': %s)",
+		c.raw.Item, c.raw.ProjectID, c.raw.Status, c.raw.BatchID, c.raw.BatchOutcome)
+}
+
+func (c *githubMergePRMessage) Raw() interface{} {
+	return c.raw
+}
+
+// githubPRLogger is a grip send.Sender that acts on successful commit queue
+// merge results: it merges the PRs on GitHub and then dequeues the item from
+// the commit queue. Messages with any other status are ignored. dryRun skips
+// the actual GitHub call, which is all the mock logger used by tests needs.
+type githubPRLogger struct {
+	*send.Base
+	token  string
+	dryRun bool
+}
+
+// NewGithubPRLogger returns a sender that merges commit queue PRs on GitHub
+// using token, and dequeues the corresponding item once the merge succeeds.
+func NewGithubPRLogger(name, token string, errorLogger send.Sender) (send.Sender, error) {
+	s := &githubPRLogger{
+		Base:  send.NewBase(name),
+		token: token,
+	}
+	if err := s.SetErrorHandler(send.ErrorHandlerFromSender(errorLogger)); err != nil {
+		return nil, errors.Wrap(err, "problem setting error handler")
+	}
+
+	return s, nil
+}
+
+// NewMockGithubPRLogger returns a sender that skips the GitHub merge call
+// but otherwise behaves like the real logger (most importantly, it still
+// dequeues the item on a successful merge message), for use in tests.
+func NewMockGithubPRLogger(name string, errorLogger send.Sender) (send.Sender, error) {
+	s := &githubPRLogger{
+		Base:   send.NewBase(name),
+		dryRun: true,
+	}
+	if err := s.SetErrorHandler(send.ErrorHandlerFromSender(errorLogger)); err != nil {
+		return nil, errors.Wrap(err, "problem setting error handler")
+	}
+
+	return s, nil
+}
+
+func (l *githubPRLogger) Send(m message.Composer) {
+	if !m.Loggable() {
+		return
+	}
+
+	raw, ok := m.Raw().(GithubMergePR)
+	if !ok {
+		l.ErrorHandler()(errors.Errorf("message is '%T', not a GithubMergePR", m.Raw()), m)
+		return
+	}
+
+	if raw.Status != evergreen.PatchSucceeded {
+		return
+	}
+
+	if !l.dryRun {
+		if err := l.mergePR(raw); err != nil {
+			l.ErrorHandler()(errors.Wrap(err, "problem merging pull request on GitHub"), m)
+			return
+		}
+	}
+
+	if err := dequeueFromCommitQueue(raw.ProjectID, raw.Item); err != nil {
+		l.ErrorHandler()(errors.Wrap(err, "problem dequeuing merged item from commit queue"), m)
+	}
+}
+
+// mergePR talks to the GitHub API to merge the PRs that make up a commit
+// queue item. It's intentionally isolated from Send so tests can exercise
+// everything else (message validation, dequeuing, error handling) without
+// needing network access or a real token.
+func (l *githubPRLogger) mergePR(msg GithubMergePR) error {
+	if l.token == "" {
+		return errors.New("no GitHub token configured for PR merge logger")
+	}
+
+	for _, pr := range msg.PRs {
+		if err := mergeGithubPR(l.token, pr); err != nil {
+			return errors.Wrapf(err, "problem merging PR #%d in '%s/%s'", pr.PRNum, pr.Owner, pr.Repo)
+		}
+	}
+
+	return nil
+}
@@ -66,3 +66,68 @@ func (s *GitHubPRSenderSuite) TestDequeueFromCommitQueue() {
 	s.NoError(err)
 	s.Equal("2", cq.Next().Issue)
 }
+
+func (s *GitHubPRSenderSuite) TestBatchedMergeSuccess() {
+	cq, err := FindOneId("mci")
+	s.Require().NoError(err)
+	batch := cq.NextBatch(2)
+	s.Require().Len(batch, 2)
+
+	errLogger := &mockErrorLogger{}
+	ghPRLogger, err := NewMockGithubPRLogger("mock_gh_pr_logger", errLogger)
+	s.NoError(err)
+
+	for _, item := range batch {
+		msg := GithubMergePR{
+			Status:       evergreen.PatchSucceeded,
+			ProjectID:    "mci",
+			Item:         item.Issue,
+			BatchID:      "batch-1",
+			BatchOutcome: "merged as part of batch batch-1",
+		}
+		ghPRLogger.Send(NewGithubMergePRMessage(level.Info, msg))
+	}
+	s.Empty(errLogger.errList)
+
+	cq, err = FindOneId("mci")
+	s.NoError(err)
+	s.Nil(cq.Next(), "both batch members should have been dequeued")
+}
+
+func (s *GitHubPRSenderSuite) TestHeadOfBatchFailure() {
+	cq, err := FindOneId("mci")
+	s.Require().NoError(err)
+	batch := cq.NextBatch(2)
+	s.Require().Len(batch, 2)
+
+	s.NoError(BisectFailedBatch("mci", batch, "1"))
+
+	cq, err = FindOneId("mci")
+	s.Require().NoError(err)
+	s.Require().Len(cq.Queue, 1, "the failing head should be ejected and the tail requeued exactly once, with nothing duplicated")
+	s.Equal("2", cq.Next().Issue, "the item after the failing head should be re-enqueued for retesting")
+}
+
+func (s *GitHubPRSenderSuite) TestPartialBatchMerge() {
+	cq := &CommitQueue{
+		ProjectID: "partial",
+		BatchSize: 3,
+		Queue: []CommitQueueItem{
+			{Issue: "10"},
+			{Issue: "11"},
+			{Issue: "12"},
+		},
+	}
+	s.NoError(db.ClearCollections(Collection))
+	s.NoError(InsertQueue(cq))
+
+	batch := cq.NextBatch(3)
+	s.Require().Len(batch, 3)
+
+	s.NoError(BisectFailedBatch("partial", batch, "11"))
+
+	updated, err := FindOneId("partial")
+	s.Require().NoError(err)
+	s.Require().Len(updated.Queue, 1, "item 10 merged, 11 was ejected, and 12 should appear exactly once, not duplicated")
+	s.Equal("12", updated.Next().Issue, "item 10 merged, 11 was ejected, 12 goes back to the queue")
+}
@@ -0,0 +1,67 @@
+package commitqueue
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// webhookSink POSTs every commit queue event as JSON to a configured URL,
+// signing the body with HMAC-SHA256 so the receiver can verify it actually
+// came from this evergreen instance.
+type webhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookSink returns a Sink that POSTs events to url, signed with
+// secret via the X-Evergreen-Signature header.
+func NewWebhookSink(url string, secret []byte) Sink {
+	return &webhookSink{
+		url:    url,
+		secret: secret,
+		client: &http.Client{},
+	}
+}
+
+func (s *webhookSink) Name() string { return "webhook" }
+
+func (s *webhookSink) Send(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "problem marshalling event")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "problem building webhook request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Evergreen-Signature", signWebhookBody(s.secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "problem sending webhook request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func signWebhookBody(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
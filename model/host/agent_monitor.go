@@ -0,0 +1,203 @@
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/jasper"
+	"github.com/pkg/errors"
+)
+
+// agentMonitorServiceName is the name the agent monitor is installed under
+// when it's supervised by the distro's init system, rather than run as a
+// bare tagged Jasper child process.
+const agentMonitorServiceName = "evergreen-agent-monitor"
+
+// AgentMonitorUnitOptions control how the generated unit/service restarts.
+// RestartSec applies to the systemd unit and the Windows service, WatchdogSec
+// only to the systemd unit; the SysV init script uses neither. The agent
+// monitor's environment comes from buildAgentEnv, not from these options.
+type AgentMonitorUnitOptions struct {
+	// RestartSec is how long the init system waits before restarting a
+	// crashed agent monitor.
+	RestartSec int
+	// WatchdogSec is the systemd watchdog interval; the agent monitor must
+	// call sd_notify(WATCHDOG=1) within this window or be restarted.
+	WatchdogSec int
+}
+
+// DefaultAgentMonitorUnitOptions are the restart/watchdog parameters used
+// unless the caller overrides them.
+var DefaultAgentMonitorUnitOptions = AgentMonitorUnitOptions{RestartSec: 10, WatchdogSec: 60}
+
+// InstallAgentMonitorUnit builds what is needed to run the agent monitor as
+// a unit supervised by the host's init system (systemd, SysV, or the Windows
+// SCM) instead of relying solely on the tagged-process model, so that a
+// crash of the Jasper service itself no longer silences the host. It does
+// not run anything on the host itself. It returns the init system chosen
+// (Windows by arch, otherwise the distro's configured InitSystem, with
+// systemd as the default) so callers (and StopAgentMonitor) know which stop
+// path to use, and the text for it: for systemd and Windows a command that
+// installs the service, for SysV the init script itself, which is not
+// written anywhere by this function.
+func (h *Host) InstallAgentMonitorUnit(settings *evergreen.Settings, opts AgentMonitorUnitOptions) (string, string, error) {
+	args, err := h.agentMonitorArgs(settings)
+	if err != nil {
+		return "", "", errors.Wrap(err, "problem building agent monitor args")
+	}
+	env := buildAgentEnv(settings)
+
+	if h.isWindows() {
+		installCmd := h.windowsServiceInstallCommand(agentMonitorServiceName, args, env, opts.RestartSec)
+		return InitSystemWindows, installCmd, nil
+	}
+
+	switch h.Distro.InitSystem {
+	case InitSystemSysV:
+		script := h.sysVAgentMonitorInitScript(args, env)
+		return InitSystemSysV, script, nil
+	default:
+		unit := h.systemdAgentMonitorUnit(args, env, opts)
+		installCmd := fmt.Sprintf("cat > '/etc/systemd/system/%s.service' <<EOF\n%s\nEOF\n%s jasper service install systemd --unit=%s",
+			agentMonitorServiceName, unit, h.jasperBinaryFilePath(evergreen.HostJasperConfig{BinaryName: "jasper_cli"}), agentMonitorServiceName)
+		return InitSystemSystemd, installCmd, nil
+	}
+}
+
+func (h *Host) agentMonitorArgs(settings *evergreen.Settings) ([]string, error) {
+	optsJSON, err := json.Marshal(h.AgentMonitorOptions(settings))
+	if err != nil {
+		return nil, errors.Wrap(err, "problem marshalling agent monitor options")
+	}
+	return []string{"agent", "monitor", "--options", string(optsJSON)}, nil
+}
+
+func (h *Host) systemdAgentMonitorUnit(args []string, env map[string]string, opts AgentMonitorUnitOptions) string {
+	var envLines []string
+	for k, v := range env {
+		envLines = append(envLines, fmt.Sprintf("Environment=%s=%s", k, v))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=Evergreen Agent Monitor
+
+[Service]
+ExecStart=%s
+Restart=on-failure
+RestartSec=%d
+WatchdogSec=%d
+%s
+
+[Install]
+WantedBy=multi-user.target
+`, strings.Join(args, " "), opts.RestartSec, opts.WatchdogSec, strings.Join(envLines, "\n"))
+}
+
+func (h *Host) sysVAgentMonitorInitScript(args []string, env map[string]string) string {
+	var envLines []string
+	for k, v := range env {
+		envLines = append(envLines, fmt.Sprintf("export %s=%s", k, v))
+	}
+	return fmt.Sprintf(`#!/bin/sh
+### BEGIN INIT INFO
+# Provides:          %s
+### END INIT INFO
+%s
+exec %s
+`, agentMonitorServiceName, strings.Join(envLines, "\n"), strings.Join(args, " "))
+}
+
+func (h *Host) windowsServiceInstallCommand(name string, args []string, env map[string]string, restartSec int) string {
+	var envFlags []string
+	for k, v := range env {
+		envFlags = append(envFlags, fmt.Sprintf("--env=%s=%s", k, v))
+	}
+	return fmt.Sprintf("%s jasper service install windows --name=%s --restart_delay=%ds %s -- %s",
+		h.jasperBinaryFilePath(evergreen.HostJasperConfig{BinaryName: "jasper_cli"}), name, restartSec, strings.Join(envFlags, " "), strings.Join(args, " "))
+}
+
+// windowsServiceStopCommand returns the command that stops a Windows
+// SCM-registered service (one installed by windowsServiceInstallCommand)
+// through the jasper CLI's own service subcommand, which wraps the Windows
+// Service Control Manager APIs the same way "service install windows" does.
+func (h *Host) windowsServiceStopCommand(name string) string {
+	return fmt.Sprintf("%s jasper service stop windows --name=%s",
+		h.jasperBinaryFilePath(evergreen.HostJasperConfig{BinaryName: "jasper_cli"}), name)
+}
+
+// StopAgentMonitor stops the agent monitor running on this host. If the
+// agent monitor is supervised by the host's init system (see
+// InstallAgentMonitorUnit), it is stopped through that init system (see
+// stopAgentMonitorService); otherwise, or if that stop fails, it falls back
+// to the legacy behavior of sending SIGTERM to the tagged Jasper child
+// process.
+func (h *Host) StopAgentMonitor(ctx context.Context, env evergreen.Environment) error {
+	if h.Distro.BootstrapMethod == distro.BootstrapMethodLegacySSH {
+		return nil
+	}
+
+	if h.AgentMonitorUnitInitSystem != "" {
+		if err := h.stopAgentMonitorService(ctx, env); err != nil {
+			grip.Warning(message.WrapError(err, message.Fields{
+				"message": "failed to stop supervised agent monitor, falling back to tagged-process kill",
+				"host_id": h.Id,
+			}))
+		} else {
+			return nil
+		}
+	}
+
+	client, err := h.JasperClient(ctx, env)
+	if err != nil {
+		return errors.Wrap(err, "problem getting Jasper client")
+	}
+
+	procs, err := client.Group(ctx, evergreen.AgentMonitorTag)
+	if err != nil {
+		return errors.Wrap(err, "problem finding tagged agent monitor processes")
+	}
+
+	catcher := grip.NewBasicCatcher()
+	for _, proc := range procs {
+		if !proc.Running(ctx) {
+			continue
+		}
+		catcher.Add(errors.Wrap(proc.Signal(ctx, syscall.SIGTERM), "problem sending SIGTERM to agent monitor"))
+	}
+	return catcher.Resolve()
+}
+
+// stopAgentMonitorService stops the init-system-supervised agent monitor by
+// running, as a Jasper process on the host, "systemctl stop" for systemd,
+// "service ... stop" for SysV, or the jasper CLI's Windows service-stop
+// subcommand for the Windows SCM.
+func (h *Host) stopAgentMonitorService(ctx context.Context, env evergreen.Environment) error {
+	var cmd string
+	switch h.AgentMonitorUnitInitSystem {
+	case InitSystemSysV:
+		cmd = fmt.Sprintf("service %s stop", agentMonitorServiceName)
+	case InitSystemSystemd:
+		cmd = fmt.Sprintf("systemctl stop %s", agentMonitorServiceName)
+	case InitSystemWindows:
+		cmd = h.windowsServiceStopCommand(agentMonitorServiceName)
+	default:
+		return errors.Errorf("unrecognized init system %q", h.AgentMonitorUnitInitSystem)
+	}
+
+	var opts *jasper.CreateOptions
+	if h.AgentMonitorUnitInitSystem == InitSystemWindows {
+		opts = &jasper.CreateOptions{Args: []string{"powershell", "-Command", cmd}}
+	} else {
+		opts = &jasper.CreateOptions{Args: []string{"sh", "-c", cmd}}
+	}
+	if _, err := h.RunJasperProcess(ctx, env, opts); err != nil {
+		return errors.Wrap(err, "problem stopping supervised agent monitor service")
+	}
+	return nil
+}
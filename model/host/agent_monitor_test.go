@@ -0,0 +1,63 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallAgentMonitorUnit(t *testing.T) {
+	settings := &evergreen.Settings{ApiUrl: "www.example.com"}
+
+	for testName, testCase := range map[string]struct {
+		h               *Host
+		expectedInit    string
+		expectedSubstrs []string
+	}{
+		"SystemdByDefaultOnLinux": {
+			h:            &Host{Id: "id", Distro: distro.Distro{CuratorDir: "/foo", User: "user"}},
+			expectedInit: InitSystemSystemd,
+			expectedSubstrs: []string{
+				"Restart=on-failure",
+				"RestartSec=10",
+				"WatchdogSec=60",
+				"jasper service install systemd",
+			},
+		},
+		"SysVWhenDistroConfiguredForIt": {
+			h:            &Host{Id: "id", Distro: distro.Distro{CuratorDir: "/foo", User: "user", InitSystem: InitSystemSysV}},
+			expectedInit: InitSystemSysV,
+			expectedSubstrs: []string{
+				"### BEGIN INIT INFO",
+				"exec",
+			},
+		},
+		"WindowsUsesSCM": {
+			h:            &Host{Id: "id", Distro: distro.Distro{Arch: distro.ArchWindowsAmd64, CuratorDir: "/foo", User: "user"}},
+			expectedInit: InitSystemWindows,
+			expectedSubstrs: []string{
+				"jasper service install windows",
+				"--name=evergreen-agent-monitor",
+			},
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			initSystem, cmd, err := testCase.h.InstallAgentMonitorUnit(settings, DefaultAgentMonitorUnitOptions)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedInit, initSystem)
+			for _, substr := range testCase.expectedSubstrs {
+				assert.Contains(t, cmd, substr)
+			}
+		})
+	}
+}
+
+func TestWindowsServiceStopCommand(t *testing.T) {
+	h := &Host{Id: "id", Distro: distro.Distro{Arch: distro.ArchWindowsAmd64, CuratorDir: "/foo", User: "user"}}
+	cmd := h.windowsServiceStopCommand(agentMonitorServiceName)
+	assert.Contains(t, cmd, "jasper service stop windows")
+	assert.Contains(t, cmd, "--name=evergreen-agent-monitor")
+}
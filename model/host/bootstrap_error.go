@@ -0,0 +1,147 @@
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/pkg/errors"
+)
+
+// BootstrapStage identifies which part of the bootstrap script a failure
+// came from, so callers don't have to guess at the meaning of a bare shell
+// exit code in cloud-init logs.
+type BootstrapStage string
+
+const (
+	BootstrapStagePreCmd         BootstrapStage = "pre_cmd"
+	BootstrapStageFetchClient    BootstrapStage = "fetch_client"
+	BootstrapStageFetchJasper    BootstrapStage = "fetch_jasper"
+	BootstrapStageWriteCreds     BootstrapStage = "write_creds"
+	BootstrapStageInstallService BootstrapStage = "install_service"
+	BootstrapStagePostCmd        BootstrapStage = "post_cmd"
+)
+
+// bootstrapExitCodeBase maps each stage to the first exit code in its
+// reserved range (e.g. pre-cmd failures exit 10-19, fetch-client failures
+// exit 20-29, ...). The generated script adds the step's index within the
+// stage to this base, clamped to the top of the range.
+var bootstrapExitCodeBase = map[BootstrapStage]int{
+	BootstrapStagePreCmd:         10,
+	BootstrapStageFetchClient:    20,
+	BootstrapStageFetchJasper:    30,
+	BootstrapStageWriteCreds:     40,
+	BootstrapStageInstallService: 50,
+	BootstrapStagePostCmd:        60,
+}
+
+const bootstrapExitCodeRangeSize = 10
+
+// bootstrapStatusFile is the well-known path the bootstrap script writes its
+// terminal status to, so it can be read back once the host is reachable.
+const bootstrapStatusFile = "/tmp/evergreen_bootstrap_status.json"
+
+// BootstrapError describes a failure that occurred during a specific stage
+// of BootstrapScript.
+type BootstrapError struct {
+	Stage      BootstrapStage `json:"stage"`
+	Code       int            `json:"code"`
+	StderrTail string         `json:"stderr_tail"`
+}
+
+func (e *BootstrapError) Error() string {
+	return fmt.Sprintf("bootstrap failed at stage '%s' (exit code %d): %s", e.Stage, e.Code, e.StderrTail)
+}
+
+// bootstrapStatus is the JSON document the generated script writes to
+// bootstrapStatusFile describing the terminal outcome of the run.
+type bootstrapStatus struct {
+	Succeeded bool            `json:"succeeded"`
+	Error     *BootstrapError `json:"error,omitempty"`
+}
+
+// wrapBootstrapStage wraps cmds (all commands belonging to a single stage)
+// so that a failure is tagged with stage's reserved exit code range and
+// recorded to bootstrapStatusFile before the script aborts.
+func wrapBootstrapStage(windows bool, stage BootstrapStage, cmds []string) []string {
+	if len(cmds) == 0 {
+		return cmds
+	}
+
+	base := bootstrapExitCodeBase[stage]
+	if windows {
+		return wrapWindowsBootstrapStage(stage, base, cmds)
+	}
+	return wrapLinuxBootstrapStage(stage, base, cmds)
+}
+
+// splitAndJoinedCommand breaks a command string built by strings.Join(cmds,
+// " && ") back into its individual sub-commands. Bash's ERR trap only fires
+// for the last command in a && list, so a multi-stage command like
+// FetchJasperCommand's output needs each sub-command wrapped separately to
+// catch a failure partway through the chain.
+func splitAndJoinedCommand(cmd string) []string {
+	return strings.Split(cmd, " && ")
+}
+
+func wrapLinuxBootstrapStage(stage BootstrapStage, base int, cmds []string) []string {
+	var wrapped []string
+	for i, cmd := range cmds {
+		code := base + i
+		if code >= base+bootstrapExitCodeRangeSize {
+			code = base + bootstrapExitCodeRangeSize - 1
+		}
+		trap := fmt.Sprintf(`trap 'rc=$?; echo "{\"succeeded\":false,\"error\":{\"stage\":\"%s\",\"code\":%d,\"stderr_tail\":\"$(tail -c 500 /tmp/evergreen_bootstrap.log 2>/dev/null)\"}}" > %s; exit %d' ERR`,
+			stage, code, bootstrapStatusFile, code)
+		for _, subCmd := range splitAndJoinedCommand(cmd) {
+			wrapped = append(wrapped, trap, subCmd, "trap - ERR")
+		}
+	}
+	return wrapped
+}
+
+func wrapWindowsBootstrapStage(stage BootstrapStage, base int, cmds []string) []string {
+	var wrapped []string
+	for i, cmd := range cmds {
+		code := base + i
+		if code >= base+bootstrapExitCodeRangeSize {
+			code = base + bootstrapExitCodeRangeSize - 1
+		}
+		wrapped = append(wrapped, fmt.Sprintf(`$ErrorActionPreference = 'Stop'
+try {
+  %s
+} catch {
+  $status = @{succeeded=$false; error=@{stage='%s'; code=%d; stderr_tail=$_.Exception.Message}} | ConvertTo-Json -Compress
+  Set-Content -Path '%s' -Value $status
+  exit %d
+}`, cmd, stage, code, bootstrapStatusFile, code))
+	}
+	return wrapped
+}
+
+// ReadBootstrapStatus pulls the bootstrap status file back from the host
+// over the Jasper RPC client and returns the BootstrapError it recorded, if
+// the run failed. It returns nil, nil if the host bootstrapped successfully.
+func (h *Host) ReadBootstrapStatus(ctx context.Context, env evergreen.Environment) (*BootstrapError, error) {
+	client, err := h.JasperClient(ctx, env)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem getting Jasper client")
+	}
+
+	output, err := client.DownloadFile(ctx, bootstrapStatusFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem reading bootstrap status file")
+	}
+
+	status := &bootstrapStatus{}
+	if err := json.Unmarshal(output, status); err != nil {
+		return nil, errors.Wrap(err, "problem unmarshalling bootstrap status")
+	}
+
+	if status.Succeeded {
+		return nil, nil
+	}
+	return status.Error, nil
+}
@@ -0,0 +1,117 @@
+package host
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapBootstrapStage(t *testing.T) {
+	for testName, testCase := range map[string]struct {
+		windows bool
+		stage   BootstrapStage
+		cmds    []string
+	}{
+		"LinuxPreCmdUsesTrapERR": {
+			stage: BootstrapStagePreCmd,
+			cmds:  []string{"foo", "bar"},
+		},
+		"WindowsFetchJasperUsesTryCatch": {
+			windows: true,
+			stage:   BootstrapStageFetchJasper,
+			cmds:    []string{"foo"},
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			wrapped := wrapBootstrapStage(testCase.windows, testCase.stage, testCase.cmds)
+			joined := ""
+			for _, w := range wrapped {
+				joined += w + "\n"
+			}
+
+			base := bootstrapExitCodeBase[testCase.stage]
+			if testCase.windows {
+				assert.Contains(t, joined, "try {")
+				assert.Contains(t, joined, "catch {")
+			} else {
+				assert.Contains(t, joined, "trap '")
+				assert.Contains(t, joined, "ERR")
+			}
+			for _, cmd := range testCase.cmds {
+				assert.Contains(t, joined, cmd)
+			}
+			assert.Contains(t, joined, string(testCase.stage))
+			assert.Contains(t, joined, bootstrapStatusFile)
+			assert.True(t, base >= bootstrapExitCodeBase[testCase.stage] && base < bootstrapExitCodeBase[testCase.stage]+bootstrapExitCodeRangeSize)
+		})
+	}
+}
+
+func TestWrapBootstrapStageEmpty(t *testing.T) {
+	assert.Empty(t, wrapBootstrapStage(false, BootstrapStagePreCmd, nil))
+}
+
+// TestWrapLinuxBootstrapStageWrapsEachAndedSubcommand guards against
+// wrapping an already-"&&"-joined command (like FetchJasperCommand's
+// output) as a single trap/cmd/trap triple: bash only fires an ERR trap for
+// the last command in a && list, so every sub-command needs its own trap.
+func TestWrapLinuxBootstrapStageWrapsEachAndedSubcommand(t *testing.T) {
+	wrapped := wrapBootstrapStage(false, BootstrapStagePreCmd, []string{"cd /tmp && false && echo should-not-run"})
+
+	require.Len(t, wrapped, 9, "3 sub-commands, each as its own trap/cmd/trap-clear triple")
+	assert.Equal(t, "cd /tmp", wrapped[1])
+	assert.Equal(t, "false", wrapped[4])
+	assert.Equal(t, "echo should-not-run", wrapped[7])
+	assert.Equal(t, 3, strings.Count(strings.Join(wrapped, "\n"), "trap '"))
+}
+
+// TestWrapLinuxBootstrapStageCatchesMidChainFailure actually runs the
+// wrapped script through bash and confirms a failure partway through a
+// multi-step "&&" chain is caught, instead of bash silently only firing the
+// ERR trap for (and reporting success based on) the chain's last command.
+func TestWrapLinuxBootstrapStageCatchesMidChainFailure(t *testing.T) {
+	require.NoError(t, os.RemoveAll(bootstrapStatusFile))
+	defer os.RemoveAll(bootstrapStatusFile)
+
+	markerFile, err := ioutil.TempFile("", "evergreen-bootstrap-test-marker")
+	require.NoError(t, err)
+	require.NoError(t, markerFile.Close())
+	require.NoError(t, os.Remove(markerFile.Name()))
+	defer os.RemoveAll(markerFile.Name())
+
+	wrapped := wrapBootstrapStage(false, BootstrapStagePreCmd, []string{
+		"false && echo should-not-run > " + markerFile.Name(),
+	})
+	script := strings.Join(wrapped, "\n")
+
+	// The trap only writes the status file on failure, so a real run of
+	// this fragment on its own won't produce the final success line that
+	// BootstrapScript appends; we only care whether the mid-chain failure
+	// was caught.
+	_ = exec.Command("bash", "-c", script).Run()
+
+	_, err = os.Stat(markerFile.Name())
+	assert.True(t, os.IsNotExist(err), "the command after the failing one in the chain should never have run")
+
+	raw, err := ioutil.ReadFile(bootstrapStatusFile)
+	require.NoError(t, err, "the ERR trap should have fired and written a status file")
+
+	status := &bootstrapStatus{}
+	require.NoError(t, json.Unmarshal(raw, status))
+	assert.False(t, status.Succeeded)
+	require.NotNil(t, status.Error)
+	assert.Equal(t, BootstrapStagePreCmd, status.Error.Stage)
+}
+
+func TestBootstrapErrorError(t *testing.T) {
+	err := &BootstrapError{Stage: BootstrapStageFetchJasper, Code: 32, StderrTail: "connection refused"}
+	assert.Contains(t, err.Error(), "fetch_jasper")
+	assert.Contains(t, err.Error(), "32")
+	assert.Contains(t, err.Error(), "connection refused")
+}
@@ -0,0 +1,118 @@
+package host
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evergreen-ci/evergreen/model/distro"
+)
+
+// Downloader generates the shell (or PowerShell) snippet that fetches a
+// single file from a URL to a destination path on the host. Distros differ
+// in which of these tools they ship, so Host builds a small preamble that
+// tries each candidate in order rather than hard-coding one.
+type Downloader interface {
+	// probeCommand returns the command used to detect whether the
+	// downloader's binary is present on PATH.
+	probeCommand() string
+	// downloadCommand returns the command that performs the download. With
+	// numRetries <= 0 it makes a single attempt and ignores maxSecs.
+	// Otherwise each tool maps the two values onto its own options: curl
+	// retries up to numRetries times within maxSecs in total; wget makes
+	// numRetries attempts in total, with maxSecs as its per-operation
+	// network timeout; Invoke-WebRequest retries up to numRetries times,
+	// waiting maxSecs/numRetries seconds between attempts. Only curl
+	// treats maxSecs as a limit on the total time spent.
+	downloadCommand(url, destFile string, numRetries, maxSecs int) string
+}
+
+type curlDownloader struct{}
+
+func (curlDownloader) probeCommand() string { return "command -v curl" }
+
+func (curlDownloader) downloadCommand(url, destFile string, numRetries, maxSecs int) string {
+	if numRetries <= 0 {
+		return fmt.Sprintf("curl -LO '%s'", url)
+	}
+	return fmt.Sprintf("curl -LO '%s' --retry %d --retry-max-time %d", url, numRetries, maxSecs)
+}
+
+type wgetDownloader struct{}
+
+func (wgetDownloader) probeCommand() string { return "command -v wget" }
+
+func (wgetDownloader) downloadCommand(url, destFile string, numRetries, maxSecs int) string {
+	if numRetries <= 0 {
+		return fmt.Sprintf("wget -O '%s' '%s'", destFile, url)
+	}
+	return fmt.Sprintf("wget -O '%s' --tries=%d --timeout=%d '%s'", destFile, numRetries, maxSecs, url)
+}
+
+type powershellInvokeWebRequestDownloader struct{}
+
+func (powershellInvokeWebRequestDownloader) probeCommand() string {
+	return "Get-Command Invoke-WebRequest"
+}
+
+func (powershellInvokeWebRequestDownloader) downloadCommand(url, destFile string, numRetries, maxSecs int) string {
+	if numRetries <= 0 {
+		return fmt.Sprintf("Invoke-WebRequest -Uri '%s' -OutFile '%s'", url, destFile)
+	}
+	return fmt.Sprintf("Invoke-WebRequest -Uri '%s' -OutFile '%s' -MaximumRetryCount %d -RetryIntervalSec %d", url, destFile, numRetries, maxSecs/max(numRetries, 1))
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// downloaders returns, in preference order, the downloaders that are
+// plausible for the given distro. Windows distros only ever get
+// Invoke-WebRequest, since curl/wget aliases aren't guaranteed to exist on
+// Windows Server Core; everything else tries curl first and falls back to
+// wget.
+func downloaders(d distro.Distro) []Downloader {
+	if strings.HasPrefix(string(d.Arch), string(distro.ArchWindowsAmd64)) {
+		return []Downloader{powershellInvokeWebRequestDownloader{}}
+	}
+	return []Downloader{curlDownloader{}, wgetDownloader{}}
+}
+
+// downloadFileCommand returns the command (or, for distros with more than
+// one plausible downloader, a probe-and-fallback snippet) that downloads url
+// to destFile.
+func (h *Host) downloadFileCommand(url, destFile string, numRetries, maxSecs int) string {
+	candidates := downloaders(h.Distro)
+	if len(candidates) == 1 {
+		return candidates[0].downloadCommand(url, destFile, numRetries, maxSecs)
+	}
+
+	var clauses []string
+	for i, d := range candidates {
+		branch := "if"
+		if i > 0 {
+			branch = "elif"
+		}
+		clauses = append(clauses, fmt.Sprintf("%s %s >/dev/null 2>&1; then %s", branch, d.probeCommand(), d.downloadCommand(url, destFile, numRetries, maxSecs)))
+	}
+	clauses = append(clauses, fmt.Sprintf("else echo 'no downloader (tried: %s) available on PATH' 1>&2; exit 1", downloaderNames(candidates)))
+
+	return strings.Join(clauses, "; ") + "; fi"
+}
+
+func downloaderNames(candidates []Downloader) string {
+	names := make([]string, 0, len(candidates))
+	for _, d := range candidates {
+		switch d.(type) {
+		case curlDownloader:
+			names = append(names, "curl")
+		case wgetDownloader:
+			names = append(names, "wget")
+		case powershellInvokeWebRequestDownloader:
+			names = append(names, "Invoke-WebRequest")
+		}
+	}
+	return strings.Join(names, ", ")
+}
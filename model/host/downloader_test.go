@@ -0,0 +1,40 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadFileCommand(t *testing.T) {
+	for testName, testCase := range map[string]struct {
+		arch     distro.ArchType
+		contains []string
+	}{
+		"LinuxProbesCurlThenWget": {
+			arch: distro.ArchLinuxAmd64,
+			contains: []string{
+				"command -v curl",
+				"curl -LO 'www.example.com/file' --retry 3 --retry-max-time 30",
+				"command -v wget",
+				"wget -O 'file' --tries=3 --timeout=30 'www.example.com/file'",
+				"no downloader (tried: curl, wget) available on PATH",
+			},
+		},
+		"WindowsUsesInvokeWebRequestDirectly": {
+			arch: distro.ArchWindowsAmd64,
+			contains: []string{
+				"Invoke-WebRequest -Uri 'www.example.com/file' -OutFile 'file' -MaximumRetryCount 3 -RetryIntervalSec 10",
+			},
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			h := &Host{Distro: distro.Distro{Arch: testCase.arch}}
+			cmd := h.downloadFileCommand("www.example.com/file", "file", 3, 30)
+			for _, expected := range testCase.contains {
+				assert.Contains(t, cmd, expected)
+			}
+		})
+	}
+}
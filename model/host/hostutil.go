@@ -0,0 +1,258 @@
+package host
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/util"
+	"github.com/mongodb/jasper/rpc"
+	"github.com/pkg/errors"
+)
+
+const (
+	// InitSystemSystemd is the name of the systemd init system.
+	InitSystemSystemd = "systemd"
+	// InitSystemSysV is the name of the SysV init system.
+	InitSystemSysV = "sysv"
+	// InitSystemUpstart is the name of the upstart init system.
+	InitSystemUpstart = "upstart"
+	// InitSystemWindows is the Windows Service Control Manager, treated as
+	// an init system for the purposes of installing/stopping the agent
+	// monitor as a supervised service.
+	InitSystemWindows = "windows"
+
+	// CurlDefaultNumRetries is the default numRetries passed to the
+	// downloader (curl, wget, or Invoke-WebRequest) fetching a file.
+	CurlDefaultNumRetries = 10
+	// CurlDefaultMaxSecs is the default maxSecs passed to the downloader
+	// fetching a file; see Downloader for how each tool uses it.
+	CurlDefaultMaxSecs = 100
+)
+
+func (h *Host) isWindows() bool {
+	return strings.HasPrefix(string(h.Distro.Arch), string(distro.ArchWindowsAmd64))
+}
+
+// ClientURL returns the URL used to fetch the evergreen client for this
+// host's distro.
+func (h *Host) ClientURL(settings *evergreen.Settings) string {
+	return fmt.Sprintf("%s/%s/%s/%s", settings.Ui.Url, settings.ClientBinariesDir, h.Distro.Arch, h.clientBinaryName())
+}
+
+func (h *Host) clientBinaryName() string {
+	if h.isWindows() {
+		return "evergreen.exe"
+	}
+	return "evergreen"
+}
+
+// CurlCommand returns a command to fetch the evergreen client, verify its
+// checksum, and make it executable.
+func (h *Host) CurlCommand(settings *evergreen.Settings) string {
+	return fmt.Sprintf("cd %s && %s && %s && chmod +x %s",
+		h.Distro.HomeDir(), h.downloadFileCommand(h.ClientURL(settings), h.clientBinaryName(), 0, 0), h.verifyClientChecksumCommand(settings), h.clientBinaryName())
+}
+
+// CurlCommandWithRetry is the same as CurlCommand but with retry parameters.
+func (h *Host) CurlCommandWithRetry(settings *evergreen.Settings, numRetries, maxSecs int) string {
+	return fmt.Sprintf("cd %s && %s && %s && chmod +x %s",
+		h.Distro.HomeDir(), h.downloadFileCommand(h.ClientURL(settings), h.clientBinaryName(), numRetries, maxSecs), h.verifyClientChecksumCommand(settings), h.clientBinaryName())
+}
+
+// verifyClientChecksumCommand returns the command that verifies the
+// downloaded evergreen client against the digest configured for this
+// distro's architecture, aborting the bootstrap with a non-zero exit code
+// if the digest doesn't match (or isn't configured, since an unverifiable
+// binary must not be trusted).
+func (h *Host) verifyClientChecksumCommand(settings *evergreen.Settings) string {
+	checksum := settings.ClientBinariesSHA256[string(h.Distro.Arch)]
+	if h.isWindows() {
+		return windowsChecksumVerifyCommand(h.clientBinaryName(), checksum)
+	}
+	return linuxChecksumVerifyCommand(h.clientBinaryName(), checksum)
+}
+
+// linuxChecksumVerifyCommand returns a shell snippet that verifies file
+// against checksum using sha256sum -c, generating the checksum file from a
+// here-doc so no separate file has to be fetched. If checksum is empty the
+// snippet always fails, since an unconfigured digest must not be treated as
+// "verification skipped".
+func linuxChecksumVerifyCommand(file, checksum string) string {
+	if checksum == "" {
+		return fmt.Sprintf("(echo 'no SHA-256 digest configured for %s' 1>&2; exit 1)", file)
+	}
+	return fmt.Sprintf("(sha256sum -c <<EOF\n%s  %s\nEOF\n) || (echo 'checksum verification failed for %s' 1>&2; exit 1)", checksum, file, file)
+}
+
+// windowsChecksumVerifyCommand returns a PowerShell snippet that hashes file
+// with CertUtil and compares the result against checksum.
+func windowsChecksumVerifyCommand(file, checksum string) string {
+	if checksum == "" {
+		return fmt.Sprintf("(Write-Error 'no SHA-256 digest configured for %s'; exit 1)", file)
+	}
+	return fmt.Sprintf(`if ((CertUtil -hashfile '%s' SHA256 | Select-String -Pattern '^[0-9a-fA-F]{64}$').Matches.Value.ToLower() -ne '%s') { Write-Error 'checksum verification failed for %s'; exit 1 }`,
+		file, strings.ToLower(checksum), file)
+}
+
+func (h *Host) jasperDownloadedFileName(config evergreen.HostJasperConfig) string {
+	return fmt.Sprintf("%s-%s-%s.tar.gz", config.DownloadFileName, h.Distro.Arch, config.Version)
+}
+
+func (h *Host) jasperBinaryFileName(config evergreen.HostJasperConfig) string {
+	if h.isWindows() {
+		return config.BinaryName + ".exe"
+	}
+	return config.BinaryName
+}
+
+func (h *Host) jasperBinaryFilePath(config evergreen.HostJasperConfig) string {
+	return fmt.Sprintf("%s/%s", h.Distro.CuratorDir, h.jasperBinaryFileName(config))
+}
+
+// fetchJasperCommands returns the sequence of commands needed to download,
+// verify, and unpack the jasper CLI binary.
+func (h *Host) fetchJasperCommands(config evergreen.HostJasperConfig) []string {
+	downloadedFile := h.jasperDownloadedFileName(config)
+	checksum := config.SHA256[string(h.Distro.Arch)]
+	downloadURL := fmt.Sprintf("%s/%s", config.URL, downloadedFile)
+	var verifyCmd string
+	if h.isWindows() {
+		verifyCmd = windowsChecksumVerifyCommand(downloadedFile, checksum)
+	} else {
+		verifyCmd = linuxChecksumVerifyCommand(downloadedFile, checksum)
+	}
+	return []string{
+		fmt.Sprintf("cd %q", h.Distro.CuratorDir),
+		h.downloadFileCommand(downloadURL, downloadedFile, CurlDefaultNumRetries, CurlDefaultMaxSecs),
+		verifyCmd,
+		fmt.Sprintf("tar xzf '%s'", downloadedFile),
+		fmt.Sprintf("chmod +x '%s'", h.jasperBinaryFileName(config)),
+		fmt.Sprintf("rm -f '%s'", downloadedFile),
+	}
+}
+
+// FetchJasperCommand returns the command to fetch the jasper CLI, joined as
+// a single shell-compatible command.
+func (h *Host) FetchJasperCommand(config evergreen.HostJasperConfig) string {
+	return strings.Join(h.fetchJasperCommands(config), " && ")
+}
+
+// FetchJasperCommandWithPath is the same as FetchJasperCommand, but prefixes
+// every command with the given PATH.
+func (h *Host) FetchJasperCommandWithPath(config evergreen.HostJasperConfig, path string) string {
+	cmds := h.fetchJasperCommands(config)
+	for i := range cmds {
+		cmds[i] = fmt.Sprintf("PATH=%s ", path) + cmds[i]
+	}
+	return strings.Join(cmds, " && ")
+}
+
+// ForceReinstallJasperCommand returns the command to force the jasper CLI to
+// reinstall itself as an RPC service.
+func (h *Host) ForceReinstallJasperCommand(config evergreen.HostJasperConfig) string {
+	binary := h.jasperBinaryFilePath(config)
+	cmd := fmt.Sprintf("%s jasper service force-reinstall rpc --host=0.0.0.0 --port=%d --creds_path=%s --user=%s",
+		binary, config.Port, h.Distro.JasperCredentialsPath, h.Distro.User)
+	if !h.isWindows() {
+		cmd = "sudo " + cmd
+	}
+	return cmd
+}
+
+// WriteJasperCredentialsFileCommand returns the command to write the given
+// Jasper credentials to the distro's configured credentials path.
+func (h *Host) WriteJasperCredentialsFileCommand(creds *rpc.Credentials) (string, error) {
+	if h.Distro.JasperCredentialsPath == "" {
+		return "", errors.New("distro does not have a Jasper credentials path")
+	}
+	exported, err := creds.Export()
+	if err != nil {
+		return "", errors.Wrap(err, "problem exporting credentials")
+	}
+	return fmt.Sprintf("cat > '%s' <<EOF\n%s\nEOF", h.Distro.JasperCredentialsPath, exported), nil
+}
+
+// BootstrapScript returns the script used to bootstrap a host with Jasper,
+// running the given pre- and post-commands around the fetch/install steps.
+// Each stage is wrapped so that a failing step tags the script's exit code
+// with that stage's reserved range and records a BootstrapError to
+// bootstrapStatusFile, which ReadBootstrapStatus later reads back over RPC.
+func (h *Host) BootstrapScript(config evergreen.HostJasperConfig, creds *rpc.Credentials, preCmds, postCmds []string) (string, error) {
+	writeCredsCmd, err := h.WriteJasperCredentialsFileCommand(creds)
+	if err != nil {
+		return "", errors.Wrap(err, "problem building credentials command")
+	}
+
+	windows := h.isWindows()
+
+	var cmds []string
+	cmds = append(cmds, wrapBootstrapStage(windows, BootstrapStagePreCmd, preCmds)...)
+
+	if windows {
+		path := "/bin"
+		fetchCmds := h.fetchJasperCommands(config)
+		for i := range fetchCmds {
+			fetchCmds[i] = fmt.Sprintf("PATH=%s %s", path, fetchCmds[i])
+		}
+		cmds = append(cmds, wrapBootstrapStage(windows, BootstrapStageFetchJasper, fetchCmds)...)
+		cmds = append(cmds, wrapBootstrapStage(windows, BootstrapStageWriteCreds, []string{writeCredsCmd})...)
+		cmds = append(cmds, wrapBootstrapStage(windows, BootstrapStageInstallService, []string{h.ForceReinstallJasperCommand(config)})...)
+	} else {
+		cmds = append(cmds, wrapBootstrapStage(windows, BootstrapStageFetchJasper, []string{h.FetchJasperCommand(config)})...)
+		cmds = append(cmds, wrapBootstrapStage(windows, BootstrapStageInstallService, []string{h.ForceReinstallJasperCommand(config)})...)
+	}
+
+	cmds = append(cmds, wrapBootstrapStage(windows, BootstrapStagePostCmd, postCmds)...)
+	cmds = append(cmds, fmt.Sprintf(`echo '{"succeeded":true}' > %s`, bootstrapStatusFile))
+
+	script := strings.Join(cmds, "\n")
+	if windows {
+		return fmt.Sprintf("<powershell>\n%s\n</powershell>", script), nil
+	}
+	return fmt.Sprintf("#!/bin/bash\n%s", script), nil
+}
+
+// initSystemCommand returns a shell command that echoes the name of the
+// init system running on the host.
+func initSystemCommand() string {
+	return `if [[ -x /sbin/init ]] && /sbin/init --version 2>/dev/null | grep -q upstart; then echo "` + InitSystemUpstart + `";` +
+		` elif [[ -x /sbin/openrc ]] || pidof sysvinit >/dev/null 2>&1; then echo "` + InitSystemSysV + `";` +
+		` else echo "` + InitSystemSystemd + `"; fi`
+}
+
+// buildLocalJasperClientRequest builds a command that invokes the jasper CLI
+// client's sub-command with the given JSON-encoded input.
+func (h *Host) buildLocalJasperClientRequest(config evergreen.HostJasperConfig, subCmd string, input interface{}) (string, error) {
+	inputBytes, err := json.Marshal(input)
+	if err != nil {
+		return "", errors.Wrap(err, "problem marshalling input")
+	}
+
+	binary := h.jasperBinaryFilePath(config)
+	return fmt.Sprintf("%s jasper client %s --service=rpc --port=%d --creds_path=%s '%s'",
+		binary, subCmd, config.Port, h.Distro.JasperCredentialsPath, string(inputBytes)), nil
+}
+
+// SetupScriptCommands returns the distro's setup script, with expansions
+// applied, unless the host was spawned by a task or has no setup script.
+func (h *Host) SetupScriptCommands(settings *evergreen.Settings) (string, error) {
+	if h.SpawnOptions.SpawnedByTask || h.Distro.Setup == "" {
+		return "", nil
+	}
+
+	exp := util.NewExpansions(settings.Expansions)
+	expanded, err := exp.ExpandString(h.Distro.Setup)
+	if err != nil {
+		return "", errors.Wrap(err, "problem expanding setup script")
+	}
+	return expanded, nil
+}
+
+// TearDownCommandOverSSH returns the command used to tear down a host over
+// SSH.
+func TearDownCommandOverSSH() string {
+	return "chmod +x teardown.sh && sh teardown.sh"
+}
@@ -2,6 +2,7 @@ package host
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -34,13 +35,36 @@ func TestCurlCommand(t *testing.T) {
 	settings := &evergreen.Settings{
 		Ui:                evergreen.UIConfig{Url: "www.example.com"},
 		ClientBinariesDir: "clients",
+		ClientBinariesSHA256: map[string]string{
+			string(distro.ArchWindowsAmd64): "deadbeef",
+			string(distro.ArchLinuxAmd64):   "c0ffee",
+		},
 	}
-	expected := "cd /home/user && curl -LO 'www.example.com/clients/windows_amd64/evergreen.exe' && chmod +x evergreen.exe"
-	assert.Equal(expected, h.CurlCommand(settings))
+	cmd := h.CurlCommand(settings)
+	assert.Contains(cmd, "cd /home/user && Invoke-WebRequest -Uri 'www.example.com/clients/windows_amd64/evergreen.exe' -OutFile 'evergreen.exe'")
+	assert.Contains(cmd, "CertUtil -hashfile 'evergreen.exe' SHA256")
+	assert.Contains(cmd, "deadbeef")
+	assert.True(strings.HasSuffix(cmd, "&& chmod +x evergreen.exe"))
 
 	h = &Host{Distro: distro.Distro{Arch: distro.ArchLinuxAmd64, User: "user"}}
-	expected = "cd /home/user && curl -LO 'www.example.com/clients/linux_amd64/evergreen' && chmod +x evergreen"
-	assert.Equal(expected, h.CurlCommand(settings))
+	cmd = h.CurlCommand(settings)
+	assert.True(strings.HasPrefix(cmd, "cd /home/user &&"))
+	assert.Contains(cmd, "curl -LO 'www.example.com/clients/linux_amd64/evergreen'")
+	assert.Contains(cmd, "wget -O 'evergreen' 'www.example.com/clients/linux_amd64/evergreen'")
+	assert.Contains(cmd, "sha256sum -c")
+	assert.Contains(cmd, "c0ffee  evergreen")
+	assert.True(strings.HasSuffix(cmd, "&& chmod +x evergreen"))
+}
+
+func TestCurlCommandRejectsMissingChecksum(t *testing.T) {
+	h := &Host{Distro: distro.Distro{Arch: distro.ArchLinuxAmd64, User: "user"}}
+	settings := &evergreen.Settings{
+		Ui:                evergreen.UIConfig{Url: "www.example.com"},
+		ClientBinariesDir: "clients",
+	}
+	cmd := h.CurlCommand(settings)
+	assert.Contains(t, cmd, "no SHA-256 digest configured")
+	assert.Contains(t, cmd, "exit 1")
 }
 
 func TestCurlCommandWithRetry(t *testing.T) {
@@ -48,13 +72,22 @@ func TestCurlCommandWithRetry(t *testing.T) {
 	settings := &evergreen.Settings{
 		Ui:                evergreen.UIConfig{Url: "www.example.com"},
 		ClientBinariesDir: "clients",
+		ClientBinariesSHA256: map[string]string{
+			string(distro.ArchWindowsAmd64): "deadbeef",
+			string(distro.ArchLinuxAmd64):   "c0ffee",
+		},
 	}
-	expected := "cd /home/user && curl -LO 'www.example.com/clients/windows_amd64/evergreen.exe' --retry 5 --retry-max-time 10 && chmod +x evergreen.exe"
-	assert.Equal(t, expected, h.CurlCommandWithRetry(settings, 5, 10))
+	cmd := h.CurlCommandWithRetry(settings, 5, 10)
+	assert.Contains(t, cmd, "cd /home/user && Invoke-WebRequest -Uri 'www.example.com/clients/windows_amd64/evergreen.exe' -OutFile 'evergreen.exe' -MaximumRetryCount 5 -RetryIntervalSec 2")
+	assert.Contains(t, cmd, "CertUtil -hashfile 'evergreen.exe' SHA256")
+	assert.True(t, strings.HasSuffix(cmd, "&& chmod +x evergreen.exe"))
 
 	h = &Host{Distro: distro.Distro{Arch: distro.ArchLinuxAmd64, User: "user"}}
-	expected = "cd /home/user && curl -LO 'www.example.com/clients/linux_amd64/evergreen' --retry 5 --retry-max-time 10 && chmod +x evergreen"
-	assert.Equal(t, expected, h.CurlCommandWithRetry(settings, 5, 10))
+	cmd = h.CurlCommandWithRetry(settings, 5, 10)
+	assert.Contains(t, cmd, "curl -LO 'www.example.com/clients/linux_amd64/evergreen' --retry 5 --retry-max-time 10")
+	assert.Contains(t, cmd, "wget -O 'evergreen' --tries=5 --timeout=10 'www.example.com/clients/linux_amd64/evergreen'")
+	assert.Contains(t, cmd, "sha256sum -c")
+	assert.True(t, strings.HasSuffix(cmd, "&& chmod +x evergreen"))
 }
 
 func TestClientURL(t *testing.T) {
@@ -78,6 +111,7 @@ func TestJasperCommands(t *testing.T) {
 			expectedCmds := []string{
 				"cd \"/foo\"",
 				fmt.Sprintf("curl -LO 'www.example.com/download_file-linux-amd64-abc123.tar.gz' --retry %d --retry-max-time %d", CurlDefaultNumRetries, CurlDefaultMaxSecs),
+				"sha256sum -c",
 				"tar xzf 'download_file-linux-amd64-abc123.tar.gz'",
 				"chmod +x 'jasper_cli'",
 				"rm -f 'download_file-linux-amd64-abc123.tar.gz'",
@@ -85,9 +119,16 @@ func TestJasperCommands(t *testing.T) {
 			cmds := h.fetchJasperCommands(config)
 			require.Len(t, cmds, len(expectedCmds))
 			for i := range expectedCmds {
-				assert.Equal(t, expectedCmds[i], cmds[i])
+				assert.Contains(t, cmds[i], expectedCmds[i])
 			}
 		},
+		"VerifyBaseFetchCommandsRejectsBadChecksum": func(t *testing.T, h *Host, config evergreen.HostJasperConfig) {
+			config.SHA256 = map[string]string{string(distro.ArchLinuxAmd64): "wrong"}
+			cmds := h.fetchJasperCommands(config)
+			verifyCmd := cmds[2]
+			assert.Contains(t, verifyCmd, "wrong")
+			assert.Contains(t, verifyCmd, "exit 1")
+		},
 		"FetchJasperCommand": func(t *testing.T, h *Host, config evergreen.HostJasperConfig) {
 			expectedCmds := h.fetchJasperCommands(config)
 			cmds := h.FetchJasperCommand(config)
@@ -158,6 +199,7 @@ func TestJasperCommands(t *testing.T) {
 				URL:              "www.example.com",
 				Version:          "abc123",
 				Port:             12345,
+				SHA256:           map[string]string{string(distro.ArchLinuxAmd64): "abc123sum"},
 			}
 			opCase(t, h, config)
 		})
@@ -169,7 +211,8 @@ func TestJasperCommandsWindows(t *testing.T) {
 		"VerifyBaseFetchCommands": func(t *testing.T, h *Host, config evergreen.HostJasperConfig) {
 			expectedCmds := []string{
 				"cd \"/foo\"",
-				fmt.Sprintf("curl -LO 'www.example.com/download_file-windows-amd64-abc123.tar.gz' --retry %d --retry-max-time %d", CurlDefaultNumRetries, CurlDefaultMaxSecs),
+				fmt.Sprintf("Invoke-WebRequest -Uri 'www.example.com/download_file-windows-amd64-abc123.tar.gz' -OutFile 'download_file-windows-amd64-abc123.tar.gz' -MaximumRetryCount %d -RetryIntervalSec %d", CurlDefaultNumRetries, CurlDefaultMaxSecs/CurlDefaultNumRetries),
+				"CertUtil -hashfile",
 				"tar xzf 'download_file-windows-amd64-abc123.tar.gz'",
 				"chmod +x 'jasper_cli.exe'",
 				"rm -f 'download_file-windows-amd64-abc123.tar.gz'",
@@ -177,7 +220,7 @@ func TestJasperCommandsWindows(t *testing.T) {
 			cmds := h.fetchJasperCommands(config)
 			require.Len(t, cmds, len(expectedCmds))
 			for i := range expectedCmds {
-				assert.Equal(t, expectedCmds[i], cmds[i])
+				assert.Contains(t, cmds[i], expectedCmds[i])
 			}
 		},
 		"FetchJasperCommand": func(t *testing.T, h *Host, config evergreen.HostJasperConfig) {
@@ -295,6 +338,7 @@ func TestJasperCommandsWindows(t *testing.T) {
 				URL:              "www.example.com",
 				Version:          "abc123",
 				Port:             12345,
+				SHA256:           map[string]string{string(distro.ArchWindowsAmd64): "abc123sum"},
 			}
 			opCase(t, h, config)
 		})
@@ -677,6 +721,58 @@ func TestStopAgentMonitor(t *testing.T) {
 			require.True(t, ok)
 			assert.Empty(t, mockProc.Signals)
 		},
+		"PrefersServiceStopWhenUnitInstalled": func(ctx context.Context, t *testing.T, env evergreen.Environment, manager *jasper.MockManager, h *Host) {
+			h.AgentMonitorUnitInitSystem = InitSystemSystemd
+
+			proc, err := manager.CreateProcess(ctx, &jasper.CreateOptions{
+				Args: []string{"agent", "monitor", "command"},
+			})
+			require.NoError(t, err)
+			proc.Tag(evergreen.AgentMonitorTag)
+
+			mockProc, ok := proc.(*jasper.MockProcess)
+			require.True(t, ok)
+			mockProc.ProcInfo.IsRunning = true
+
+			require.NoError(t, h.StopAgentMonitor(ctx, env))
+
+			assert.Empty(t, mockProc.Signals, "supervised agent monitor should be stopped via the service, not SIGTERM")
+		},
+		"PrefersServiceStopWhenUnitInstalledOnWindows": func(ctx context.Context, t *testing.T, env evergreen.Environment, manager *jasper.MockManager, h *Host) {
+			h.AgentMonitorUnitInitSystem = InitSystemWindows
+
+			proc, err := manager.CreateProcess(ctx, &jasper.CreateOptions{
+				Args: []string{"agent", "monitor", "command"},
+			})
+			require.NoError(t, err)
+			proc.Tag(evergreen.AgentMonitorTag)
+
+			mockProc, ok := proc.(*jasper.MockProcess)
+			require.True(t, ok)
+			mockProc.ProcInfo.IsRunning = true
+
+			require.NoError(t, h.StopAgentMonitor(ctx, env))
+
+			assert.Empty(t, mockProc.Signals, "supervised agent monitor on Windows should be stopped via the SCM, not SIGTERM")
+		},
+		"FallsBackToTaggedKillWhenInitSystemUnrecognized": func(ctx context.Context, t *testing.T, env evergreen.Environment, manager *jasper.MockManager, h *Host) {
+			h.AgentMonitorUnitInitSystem = "bogus"
+
+			proc, err := manager.CreateProcess(ctx, &jasper.CreateOptions{
+				Args: []string{"agent", "monitor", "command"},
+			})
+			require.NoError(t, err)
+			proc.Tag(evergreen.AgentMonitorTag)
+
+			mockProc, ok := proc.(*jasper.MockProcess)
+			require.True(t, ok)
+			mockProc.ProcInfo.IsRunning = true
+
+			require.NoError(t, h.StopAgentMonitor(ctx, env))
+
+			require.Len(t, mockProc.Signals, 1)
+			assert.Equal(t, syscall.SIGTERM, mockProc.Signals[0])
+		},
 		"NoopsOnLegacyHost": func(ctx context.Context, t *testing.T, env evergreen.Environment, manager *jasper.MockManager, h *Host) {
 			h.Distro = distro.Distro{
 				BootstrapMethod:     distro.BootstrapMethodLegacySSH,
@@ -724,13 +820,69 @@ func TestStopAgentMonitor(t *testing.T) {
 }
 
 func TestSetupSpawnHostCommand(t *testing.T) {
+	for testName, testCase := range map[string]struct {
+		arch distro.Arch
+	}{
+		"LinuxAmd64":   {arch: distro.ArchLinuxAmd64},
+		"LinuxArm64":   {arch: distro.ArchLinuxArm64},
+		"DarwinAmd64":  {arch: distro.ArchDarwinAmd64},
+		"WindowsAmd64": {arch: distro.ArchWindowsAmd64},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			require.NoError(t, db.ClearCollections(Collection, user.Collection))
+			defer func() {
+				assert.NoError(t, db.ClearCollections(Collection, user.Collection))
+			}()
+
+			dbUser := user.DBUser{Id: "user", APIKey: "key"}
+			require.NoError(t, dbUser.Insert())
+
+			h := &Host{Id: "host",
+				Distro: distro.Distro{
+					Arch:    testCase.arch,
+					WorkDir: "/dir",
+					User:    "user",
+				},
+				ProvisionOptions: &ProvisionOptions{
+					OwnerId: dbUser.Id,
+				},
+			}
+			require.NoError(t, h.Insert())
+
+			settings := &evergreen.Settings{
+				ApiUrl: "www.example0.com",
+				Ui: evergreen.UIConfig{
+					Url: "www.example1.com",
+				},
+			}
+
+			cmd, err := h.SetupSpawnHostCommand(settings)
+			require.NoError(t, err)
+			assert.Contains(t, cmd, `"api_key":"key"`)
+			assert.Contains(t, cmd, `"api_server_host":"www.example0.com/api"`)
+			assert.Contains(t, cmd, `"ui_server_host":"www.example1.com"`)
+			assert.Contains(t, cmd, `"user":"user"`)
+
+			h.ProvisionOptions.TaskId = "task_id"
+			cmdWithTask, err := h.SetupSpawnHostCommand(settings)
+			require.NoError(t, err)
+			assert.Contains(t, cmdWithTask, "task_id")
+			assert.True(t, strings.HasPrefix(cmdWithTask, cmd), "fetch command should be appended, not replace the base command")
+		})
+	}
+}
+
+// TestSetupSpawnHostCommandMatchesBaseline locks down the exact command
+// produced for the original (Linux, bash) case so a template refactor can't
+// silently change the one-liner every existing spawn host already relies on.
+func TestSetupSpawnHostCommandMatchesBaseline(t *testing.T) {
 	require.NoError(t, db.ClearCollections(Collection, user.Collection))
 	defer func() {
 		assert.NoError(t, db.ClearCollections(Collection, user.Collection))
 	}()
 
-	user := user.DBUser{Id: "user", APIKey: "key"}
-	require.NoError(t, user.Insert())
+	dbUser := user.DBUser{Id: "user", APIKey: "key"}
+	require.NoError(t, dbUser.Insert())
 
 	h := &Host{Id: "host",
 		Distro: distro.Distro{
@@ -739,7 +891,7 @@ func TestSetupSpawnHostCommand(t *testing.T) {
 			User:    "user",
 		},
 		ProvisionOptions: &ProvisionOptions{
-			OwnerId: user.Id,
+			OwnerId: dbUser.Id,
 		},
 	}
 	require.NoError(t, h.Insert())
@@ -764,6 +916,116 @@ func TestSetupSpawnHostCommand(t *testing.T) {
 	assert.Equal(t, expected, cmd)
 }
 
+func TestSetupSpawnHostCommandWithDockerCompose(t *testing.T) {
+	require.NoError(t, db.ClearCollections(Collection, user.Collection))
+	defer func() {
+		assert.NoError(t, db.ClearCollections(Collection, user.Collection))
+	}()
+
+	dbUser := user.DBUser{Id: "user", APIKey: "key"}
+	require.NoError(t, dbUser.Insert())
+
+	compose := []byte("version: '3'\nservices:\n  web:\n    image: nginx\n")
+	h := &Host{Id: "host",
+		Distro: distro.Distro{
+			Arch:    distro.ArchLinuxAmd64,
+			WorkDir: "/dir",
+			User:    "user",
+		},
+		ProvisionOptions: &ProvisionOptions{
+			OwnerId:              dbUser.Id,
+			DockerCompose:        compose,
+			DockerComposeProject: "myproject",
+		},
+	}
+	require.NoError(t, h.Insert())
+
+	settings := &evergreen.Settings{
+		ApiUrl: "www.example0.com",
+		Ui:     evergreen.UIConfig{Url: "www.example1.com"},
+	}
+
+	cmd, err := h.SetupSpawnHostCommand(settings)
+	require.NoError(t, err)
+
+	assert.Contains(t, cmd, "command -v docker >/dev/null 2>&1 || curl -fsSL https://get.docker.com | sh")
+	assert.Contains(t, cmd, "command -v docker-compose >/dev/null 2>&1")
+	assert.Contains(t, cmd, "COMPOSE_HTTP_TIMEOUT=600 DOCKER_CLIENT_TIMEOUT=600")
+	assert.Contains(t, cmd, "docker-compose -p myproject -f /home/user/docker-compose.yml up -d")
+	assert.Contains(t, cmd, base64.StdEncoding.EncodeToString(compose))
+}
+
+func TestSetupSpawnHostCommandWithDockerComposeOnDarwin(t *testing.T) {
+	require.NoError(t, db.ClearCollections(Collection, user.Collection))
+	defer func() {
+		assert.NoError(t, db.ClearCollections(Collection, user.Collection))
+	}()
+
+	dbUser := user.DBUser{Id: "user", APIKey: "key"}
+	require.NoError(t, dbUser.Insert())
+
+	compose := []byte("version: '3'\nservices:\n  web:\n    image: nginx\n")
+	h := &Host{Id: "host",
+		Distro: distro.Distro{
+			Arch:    distro.ArchDarwinAmd64,
+			WorkDir: "/dir",
+			User:    "user",
+		},
+		ProvisionOptions: &ProvisionOptions{
+			OwnerId:              dbUser.Id,
+			DockerCompose:        compose,
+			DockerComposeProject: "myproject",
+		},
+	}
+	require.NoError(t, h.Insert())
+
+	settings := &evergreen.Settings{
+		ApiUrl: "www.example0.com",
+		Ui:     evergreen.UIConfig{Url: "www.example1.com"},
+	}
+
+	cmd, err := h.SetupSpawnHostCommand(settings)
+	require.NoError(t, err)
+
+	assert.Contains(t, cmd, "command -v docker-compose >/dev/null 2>&1")
+	assert.Contains(t, cmd, "install Docker Desktop")
+	assert.Contains(t, cmd, "docker-compose -p myproject -f /home/user/docker-compose.yml up -d")
+	assert.Contains(t, cmd, base64.StdEncoding.EncodeToString(compose))
+	assert.NotContains(t, cmd, "get.docker.com", "darwin shouldn't attempt the linux-only unattended docker install")
+}
+
+func TestSetupSpawnHostCommandWithDockerComposeOnWindowsErrors(t *testing.T) {
+	require.NoError(t, db.ClearCollections(Collection, user.Collection))
+	defer func() {
+		assert.NoError(t, db.ClearCollections(Collection, user.Collection))
+	}()
+
+	dbUser := user.DBUser{Id: "user", APIKey: "key"}
+	require.NoError(t, dbUser.Insert())
+
+	h := &Host{Id: "host",
+		Distro: distro.Distro{
+			Arch:    distro.ArchWindowsAmd64,
+			WorkDir: "/dir",
+			User:    "user",
+		},
+		ProvisionOptions: &ProvisionOptions{
+			OwnerId:       dbUser.Id,
+			DockerCompose: []byte("version: '3'\n"),
+		},
+	}
+	require.NoError(t, h.Insert())
+
+	settings := &evergreen.Settings{
+		ApiUrl: "www.example0.com",
+		Ui:     evergreen.UIConfig{Url: "www.example1.com"},
+	}
+
+	_, err := h.SetupSpawnHostCommand(settings)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported on Windows")
+}
+
 func newMockCredentials() (*rpc.Credentials, error) {
 	return rpc.NewCredentials([]byte("foo"), []byte("bar"), []byte("bat"))
 }
@@ -781,11 +1043,10 @@ func setupCredentialsCollection(ctx context.Context, env *mock.Environment) erro
 }
 
 // setupJasperService performs the necessary setup to start a local Jasper
-// service associated with this host.
+// service associated with this host, routing the insert/generate/start/save
+// sequence through host.ProvisionJasperService so the rollback-on-failure
+// orchestrator it provides is actually exercised by this test suite.
 func setupJasperService(ctx context.Context, env *mock.Environment, manager *jasper.MockManager, h *Host) (jasper.CloseFunc, error) {
-	if err := h.Insert(); err != nil {
-		return nil, errors.WithStack(err)
-	}
 	port := testutil.NextPort()
 	addr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("localhost:%d", port))
 	if err != nil {
@@ -793,16 +1054,18 @@ func setupJasperService(ctx context.Context, env *mock.Environment, manager *jas
 	}
 	env.Settings().HostJasper.Port = port
 
-	creds, err := h.GenerateJasperCredentials(ctx, env)
-	if err != nil {
-		return nil, errors.WithStack(err)
+	var closeService jasper.CloseFunc
+	startService := func(ctx context.Context, creds *rpc.Credentials) (jasper.CloseFunc, error) {
+		closeService, err = rpc.StartService(ctx, manager, addr, creds)
+		return closeService, err
 	}
 
-	closeService, err := rpc.StartService(ctx, manager, addr, creds)
-	if err != nil {
-		return nil, errors.WithStack(err)
+	if err := h.ProvisionJasperService(ctx, env, startService); err != nil {
+		return closeService, errors.WithStack(err)
 	}
-	return closeService, errors.WithStack(h.SaveJasperCredentials(ctx, env, creds))
+
+	readyOpts := WaitForJasperReadyOptions{MinNumAttempts: 5, MinDelay: 10 * time.Millisecond, Timeout: 5 * time.Second}
+	return closeService, errors.Wrap(h.WaitForJasperReady(ctx, env, readyOpts), "Jasper service never became ready in test setup")
 }
 
 // teardownJasperService cleans up after a Jasper service has been set up for a
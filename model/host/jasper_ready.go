@@ -0,0 +1,134 @@
+package host
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/pkg/errors"
+)
+
+// WaitForJasperReadyOptions configures the retry/backoff strategy used by
+// WaitForJasperReady.
+type WaitForJasperReadyOptions struct {
+	// MinNumAttempts is the minimum number of dial attempts made before
+	// giving up. If Timeout is too short to fit MinNumAttempts worth of
+	// backoff, the effective deadline is extended to cover them; otherwise
+	// Timeout is unaffected and still bounds how long waiting can take.
+	MinNumAttempts int
+	// MinDelay is the initial delay between attempts; each subsequent
+	// attempt doubles it, plus jitter.
+	MinDelay time.Duration
+	// Timeout bounds the total time spent waiting, except where extended
+	// to honor MinNumAttempts; see MinNumAttempts.
+	Timeout time.Duration
+}
+
+// minAttemptsDeadline returns how long the backoff schedule needs, in the
+// worst case (full jitter every attempt), to complete opts.MinNumAttempts
+// dial attempts.
+func (opts WaitForJasperReadyOptions) minAttemptsDeadline() time.Duration {
+	if opts.MinNumAttempts <= 1 {
+		return 0
+	}
+
+	var cumulativeDelay time.Duration
+	delay := opts.MinDelay
+	for i := 0; i < opts.MinNumAttempts-1; i++ {
+		// Each wait can take up to 2x delay once jitter (itself up to
+		// delay) is added on top.
+		cumulativeDelay += 2 * delay
+		delay *= 2
+	}
+	return cumulativeDelay
+}
+
+// DefaultWaitForJasperReadyOptions asks for at least 8 attempts, which in
+// practice is enough for a freshly provisioned host's Jasper service to
+// start accepting TLS connections. With a 1-second initial delay doubling
+// on every retryable failure, guaranteeing those 8 attempts can take
+// several minutes in the worst case (full jitter on every wait), well
+// past the nominal 2-minute Timeout; see MinNumAttempts and
+// minAttemptsDeadline.
+var DefaultWaitForJasperReadyOptions = WaitForJasperReadyOptions{
+	MinNumAttempts: 8,
+	MinDelay:       time.Second,
+	Timeout:        2 * time.Minute,
+}
+
+// WaitForJasperReady blocks until the host's Jasper RPC service accepts
+// connections and round-trips a no-op call, or the effective deadline
+// (opts.Timeout, extended if needed to cover opts.MinNumAttempts) elapses.
+// Freshly provisioned hosts are frequently not ready to accept TLS
+// connections for tens of seconds, and without this, transient EOF/dial
+// errors fail provisioning outright instead of retrying.
+func (h *Host) WaitForJasperReady(ctx context.Context, env evergreen.Environment, opts WaitForJasperReadyOptions) error {
+	effectiveTimeout := opts.Timeout
+	if minDeadline := opts.minAttemptsDeadline(); minDeadline > effectiveTimeout {
+		effectiveTimeout = minDeadline
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, effectiveTimeout)
+	defer cancel()
+
+	delay := opts.MinDelay
+	var attempt int
+	var lastErr error
+	for {
+		attempt++
+
+		lastErr = h.pingJasper(ctx, env)
+		if lastErr == nil {
+			return nil
+		}
+
+		if !isRetryableJasperDialError(lastErr) {
+			return errors.Wrapf(lastErr, "non-retryable error connecting to Jasper service on host '%s'", h.Id)
+		}
+
+		jitter := time.Duration(rand.Int63n(int64(delay) + 1))
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(lastErr, "Jasper service on host '%s' was not ready after %d attempts", h.Id, attempt)
+		case <-time.After(delay + jitter):
+		}
+		delay *= 2
+	}
+}
+
+// pingJasper dials the host's Jasper RPC client and issues a lightweight
+// no-op call to confirm the round trip actually works, not just that a
+// client object could be constructed.
+func (h *Host) pingJasper(ctx context.Context, env evergreen.Environment) error {
+	client, err := h.JasperClient(ctx, env)
+	if err != nil {
+		return errors.Wrap(err, "problem getting Jasper client")
+	}
+
+	if _, err := client.ID(ctx); err != nil {
+		return errors.Wrap(err, "problem pinging Jasper service")
+	}
+	return nil
+}
+
+// isRetryableJasperDialError reports whether err looks like a transient
+// startup condition (connection not yet listening, handshake not yet
+// negotiable) as opposed to a permanent misconfiguration.
+func isRetryableJasperDialError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	cause := errors.Cause(err)
+	if cause == io.EOF || cause == context.DeadlineExceeded {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "handshake") ||
+		strings.Contains(msg, "connection refused") ||
+		strings.Contains(msg, "eof")
+}
@@ -0,0 +1,91 @@
+package host
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/mock"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/mongodb/jasper"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryableJasperDialError(t *testing.T) {
+	for testName, testCase := range map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"EOF":               {err: io.EOF, retryable: true},
+		"WrappedEOF":        {err: errors.Wrap(io.EOF, "problem dialing"), retryable: true},
+		"DeadlineExceeded":  {err: context.DeadlineExceeded, retryable: true},
+		"HandshakeFailure":  {err: errors.New("tls: handshake failure"), retryable: true},
+		"ConnectionRefused": {err: errors.New("dial tcp: connection refused"), retryable: true},
+		"PermissionDenied":  {err: errors.New("rpc error: permission denied"), retryable: false},
+		"Nil":               {err: nil, retryable: false},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testCase.retryable, isRetryableJasperDialError(testCase.err))
+		})
+	}
+}
+
+func TestMinAttemptsDeadlineCoversWorstCaseJitterForEveryAttempt(t *testing.T) {
+	for testName, testCase := range map[string]struct {
+		opts     WaitForJasperReadyOptions
+		expected time.Duration
+	}{
+		"ZeroAttemptsNeedsNoDeadline": {
+			opts:     WaitForJasperReadyOptions{MinNumAttempts: 0, MinDelay: time.Second},
+			expected: 0,
+		},
+		"SingleAttemptNeedsNoWait": {
+			opts:     WaitForJasperReadyOptions{MinNumAttempts: 1, MinDelay: time.Second},
+			expected: 0,
+		},
+		"EachAdditionalAttemptDoublesAndAddsWorstCaseJitter": {
+			// Two waits are needed to reach 3 attempts: the first up to
+			// 2x the initial delay, the second (after doubling) up to 2x
+			// that.
+			opts:     WaitForJasperReadyOptions{MinNumAttempts: 3, MinDelay: time.Second},
+			expected: 2*time.Second + 4*time.Second,
+		},
+		"DefaultOptionsNeedLongerThanTheirOwnTimeout": {
+			opts:     DefaultWaitForJasperReadyOptions,
+			expected: 2 * (time.Second) * (1<<7 - 1),
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, testCase.opts.minAttemptsDeadline())
+		})
+	}
+
+	assert.Greater(t, DefaultWaitForJasperReadyOptions.minAttemptsDeadline(), DefaultWaitForJasperReadyOptions.Timeout,
+		"this case is exactly why WaitForJasperReady must extend its effective deadline past Timeout")
+}
+
+func TestWaitForJasperReadySucceedsOnceServiceIsUp(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	env := &mock.Environment{}
+	require.NoError(t, env.Configure(ctx, "", nil))
+
+	manager := &jasper.MockManager{}
+	h := &Host{
+		Id: "test-host",
+		Distro: distro.Distro{
+			BootstrapMethod:     distro.BootstrapMethodUserData,
+			CommunicationMethod: distro.CommunicationMethodRPC,
+		},
+		Host: "localhost",
+	}
+
+	assert.NoError(t, withJasperServiceSetupAndTeardown(ctx, env, manager, h, func() {
+		opts := WaitForJasperReadyOptions{MinNumAttempts: 1, MinDelay: 10 * time.Millisecond, Timeout: 2 * time.Second}
+		assert.NoError(t, h.WaitForJasperReady(ctx, env, opts))
+	}))
+}
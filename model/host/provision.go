@@ -0,0 +1,121 @@
+package host
+
+import (
+	"context"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/jasper"
+	"github.com/mongodb/jasper/rpc"
+	"github.com/pkg/errors"
+)
+
+// ProvisionStep is one named, individually-rollback-able step of a
+// multi-step provisioning sequence. Run performs the step and returns a
+// rollback closure that undoes it (or nil if there is nothing to undo);
+// Provision calls the rollback of every step that already succeeded if a
+// later step fails, and also the failing step's own rollback if it returned
+// one along with its error.
+type ProvisionStep struct {
+	Name string
+	Run  func(ctx context.Context) (rollback func(ctx context.Context) error, err error)
+}
+
+// Provision runs steps in order and, if any step fails, runs the rollback of
+// every already-completed step and of the failing step itself, if it
+// returned one (in reverse order), so a host
+// never ends up credentialed-but-uninserted or inserted-but-uncredentialed.
+// ProvisionJasperService is the only caller so far; setupJasperService (in
+// the host package's test suite) has been rewired to go through it instead
+// of the hand-rolled insert/generate/start/save sequence it used before.
+func (h *Host) Provision(ctx context.Context, env evergreen.Environment, steps ...ProvisionStep) error {
+	var rollbacks []func(ctx context.Context) error
+
+	for _, step := range steps {
+		rollback, err := step.Run(ctx)
+		if rollback != nil {
+			rollbacks = append(rollbacks, rollback)
+		}
+		if err != nil {
+			h.rollbackProvisioning(ctx, rollbacks)
+			return errors.Wrapf(err, "problem running provisioning step '%s' for host '%s'", step.Name, h.Id)
+		}
+	}
+
+	return nil
+}
+
+func (h *Host) rollbackProvisioning(ctx context.Context, rollbacks []func(ctx context.Context) error) {
+	catcher := grip.NewBasicCatcher()
+	for i := len(rollbacks) - 1; i >= 0; i-- {
+		catcher.Add(rollbacks[i](ctx))
+	}
+	grip.Error(message.WrapError(catcher.Resolve(), message.Fields{
+		"message": "problem rolling back partially-completed host provisioning",
+		"host_id": h.Id,
+	}))
+}
+
+// ProvisionJasperService runs the insert-host, generate-credentials,
+// start-service, save-credentials sequence through Provision, so that if
+// e.g. SaveJasperCredentials fails after startService already succeeded, the
+// started service is closed, the generated credentials are deleted, and the
+// host document is removed instead of left as orphaned state in
+// host.Collection / credentials.Collection.
+func (h *Host) ProvisionJasperService(ctx context.Context, env evergreen.Environment, startService func(ctx context.Context, creds *rpc.Credentials) (jasper.CloseFunc, error)) error {
+	var creds *rpc.Credentials
+	var closeService jasper.CloseFunc
+
+	return h.Provision(ctx, env,
+		ProvisionStep{
+			Name: "insert host",
+			Run: func(ctx context.Context) (func(ctx context.Context) error, error) {
+				if err := h.Insert(); err != nil {
+					return nil, errors.Wrap(err, "problem inserting host")
+				}
+				return func(ctx context.Context) error {
+					return errors.Wrap(h.Remove(), "problem removing host after failed provisioning")
+				}, nil
+			},
+		},
+		ProvisionStep{
+			Name: "generate Jasper credentials",
+			Run: func(ctx context.Context) (func(ctx context.Context) error, error) {
+				generated, err := h.GenerateJasperCredentials(ctx, env)
+				if err != nil {
+					return nil, errors.Wrap(err, "problem generating Jasper credentials")
+				}
+				creds = generated
+				return func(ctx context.Context) error {
+					return errors.Wrap(h.DeleteJasperCredentials(ctx, env), "problem removing Jasper credentials after failed provisioning")
+				}, nil
+			},
+		},
+		ProvisionStep{
+			Name: "start Jasper service",
+			Run: func(ctx context.Context) (func(ctx context.Context) error, error) {
+				close, err := startService(ctx, creds)
+				if err != nil {
+					return nil, errors.Wrap(err, "problem starting Jasper service")
+				}
+				closeService = close
+				return func(ctx context.Context) error {
+					if closeService == nil {
+						return nil
+					}
+					return errors.Wrap(closeService(), "problem closing Jasper service after failed provisioning")
+				}, nil
+			},
+		},
+		ProvisionStep{
+			Name: "save Jasper credentials",
+			Run: func(ctx context.Context) (func(ctx context.Context) error, error) {
+				if err := h.SaveJasperCredentials(ctx, env, creds); err != nil {
+					return nil, errors.Wrap(err, "problem saving Jasper credentials")
+				}
+				return nil, nil
+			},
+		},
+	)
+}
@@ -0,0 +1,123 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/mock"
+	"github.com/evergreen-ci/evergreen/model/credentials"
+	"github.com/mongodb/jasper"
+	"github.com/mongodb/jasper/rpc"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	mgobson "gopkg.in/mgo.v2/bson"
+)
+
+func TestProvisionRollsBackOnFailureAtEachStep(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	env := &mock.Environment{}
+	require.NoError(t, env.Configure(ctx, "", nil))
+
+	h := &Host{Id: "test-host"}
+
+	for failAt := 0; failAt < 3; failAt++ {
+		t.Run(fmt.Sprintf("FailsAtStep%d", failAt), func(t *testing.T) {
+			var rolledBack []string
+			var ran []string
+
+			steps := make([]ProvisionStep, 3)
+			for i := range steps {
+				i := i
+				steps[i] = ProvisionStep{
+					Name: fmt.Sprintf("step-%d", i),
+					Run: func(ctx context.Context) (func(ctx context.Context) error, error) {
+						ran = append(ran, fmt.Sprintf("step-%d", i))
+						rollback := func(ctx context.Context) error {
+							rolledBack = append(rolledBack, fmt.Sprintf("step-%d", i))
+							return nil
+						}
+						if i == failAt {
+							return rollback, errors.New("intentional failure")
+						}
+						return rollback, nil
+					},
+				}
+			}
+
+			err := h.Provision(ctx, env, steps...)
+			require.Error(t, err)
+
+			assert.Equal(t, failAt+1, len(ran), "steps after the failing one should not run")
+
+			expectedRollbacks := make([]string, 0, failAt+1)
+			for i := failAt; i >= 0; i-- {
+				expectedRollbacks = append(expectedRollbacks, fmt.Sprintf("step-%d", i))
+			}
+			assert.Equal(t, expectedRollbacks, rolledBack, "rollbacks should run in reverse order, including the failing step's own rollback")
+		})
+	}
+}
+
+func TestProvisionSucceedsWithoutRollback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	env := &mock.Environment{}
+	require.NoError(t, env.Configure(ctx, "", nil))
+
+	h := &Host{Id: "test-host"}
+
+	var rolledBack bool
+	err := h.Provision(ctx, env, ProvisionStep{
+		Name: "noop",
+		Run: func(ctx context.Context) (func(ctx context.Context) error, error) {
+			return func(ctx context.Context) error {
+				rolledBack = true
+				return nil
+			}, nil
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, rolledBack)
+}
+
+func TestProvisionJasperServiceDeletesCredentialsWhenStartServiceFails(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	env := &mock.Environment{}
+	require.NoError(t, env.Configure(ctx, "", nil))
+	require.NoError(t, setupCredentialsCollection(ctx, env))
+	defer func() {
+		assert.NoError(t, db.ClearCollections(credentials.Collection, Collection))
+	}()
+
+	h := &Host{Id: "test-host-jasper-service"}
+
+	// The credentials collection already holds the documents written by
+	// credentials.Bootstrap, so compare against that baseline rather than
+	// against an empty collection.
+	countBefore, err := db.Count(credentials.Collection, db.Query(mgobson.M{}))
+	require.NoError(t, err)
+
+	startService := func(ctx context.Context, creds *rpc.Credentials) (jasper.CloseFunc, error) {
+		return nil, errors.New("intentional failure starting Jasper service")
+	}
+
+	err = h.ProvisionJasperService(ctx, env, startService)
+	require.Error(t, err)
+
+	count, err := db.Count(credentials.Collection, db.Query(mgobson.M{}))
+	require.NoError(t, err)
+	assert.Equal(t, countBefore, count, "Jasper credentials generated during the failed provisioning attempt should have been deleted on rollback")
+
+	foundHost, err := FindOneId(h.Id)
+	require.NoError(t, err)
+	assert.Nil(t, foundHost, "host inserted during the failed provisioning attempt should have been removed on rollback")
+}
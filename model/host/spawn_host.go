@@ -0,0 +1,82 @@
+package host
+
+import (
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/user"
+	"github.com/pkg/errors"
+)
+
+// ProvisionOptions captures the CLI setup a spawn host should run once it's
+// reachable: which user it belongs to, which task (if any) it should fetch
+// artifacts for, and optionally a set of containers it should come up
+// already running.
+type ProvisionOptions struct {
+	// OwnerId is the ID of the user who owns this host.
+	OwnerId string
+	// TaskId, if set, is fetched (source and artifacts) onto the host as
+	// part of setup.
+	TaskId string
+	// DockerCompose, if set, is the raw contents of a docker-compose.yml
+	// the host should bring up as part of setup.
+	DockerCompose []byte
+	// DockerComposeProject names the compose project (the -p flag), so
+	// repeated spawn requests against the same compose file don't clash.
+	DockerComposeProject string
+}
+
+// SetupSpawnHostCommand returns the command that configures the evergreen
+// CLI on a freshly spawned host: it writes a .evergreen.yml with the
+// caller's API key, puts the evergreen binary on PATH, optionally fetches a
+// task's source/artifacts, and (if ProvisionOptions.DockerCompose is set)
+// brings up the attached compose file.
+//
+// The actual provisioning command is rendered by the
+// SpawnHostBootstrapTemplate registered for the distro's arch, since the
+// shell/profile conventions differ enough between Linux, macOS, and Windows
+// that no single hardcoded one-liner works everywhere.
+func (h *Host) SetupSpawnHostCommand(settings *evergreen.Settings) (string, error) {
+	if h.ProvisionOptions == nil {
+		return "", errors.New("host has no provision options")
+	}
+
+	u, err := user.FindOne(user.ById(h.ProvisionOptions.OwnerId))
+	if err != nil {
+		return "", errors.Wrap(err, "problem finding host owner")
+	}
+	if u == nil {
+		return "", errors.Errorf("no user '%s' found", h.ProvisionOptions.OwnerId)
+	}
+
+	tmpl, err := spawnHostBootstrapTemplateFor(string(h.Distro.Arch))
+	if err != nil {
+		return "", errors.Wrap(err, "problem finding spawn host bootstrap template")
+	}
+
+	home := h.Distro.HomeDir()
+
+	cmd, err := tmpl.Render(home, SpawnHostBootstrapData{
+		APIKey:         u.APIKey,
+		APIServerHost:  settings.ApiUrl + "/api",
+		UIServerHost:   settings.Ui.Url,
+		User:           u.Id,
+		WorkDir:        h.Distro.WorkDir,
+		TaskID:         h.ProvisionOptions.TaskId,
+		FetchArtifacts: h.ProvisionOptions.TaskId != "",
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "problem rendering spawn host bootstrap command")
+	}
+
+	if len(h.ProvisionOptions.DockerCompose) > 0 {
+		composeCmd, err := tmpl.DockerComposeUpCommand(home, DockerComposeData{
+			Compose: h.ProvisionOptions.DockerCompose,
+			Project: h.ProvisionOptions.DockerComposeProject,
+		})
+		if err != nil {
+			return "", errors.Wrap(err, "problem building docker-compose setup command")
+		}
+		cmd += " && " + composeCmd
+	}
+
+	return cmd, nil
+}
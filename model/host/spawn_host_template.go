@@ -0,0 +1,241 @@
+package host
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SpawnHostBootstrapData is the information a SpawnHostBootstrapTemplate
+// needs to render the command that puts the evergreen CLI on PATH for a
+// spawn host's user and (optionally) fetches a task's source/artifacts.
+type SpawnHostBootstrapData struct {
+	APIKey         string
+	APIServerHost  string
+	UIServerHost   string
+	User           string
+	WorkDir        string
+	TaskID         string
+	FetchArtifacts bool
+	// ExtraEnv is written into the user's shell profile alongside PATH, so
+	// admins can inject distro-specific environment without patching Go
+	// code.
+	ExtraEnv map[string]string
+}
+
+// DockerComposeData is the information a SpawnHostBootstrapTemplate needs to
+// render the command that brings up a user-supplied docker-compose file on
+// a spawn host.
+type DockerComposeData struct {
+	// Compose is the raw contents of the docker-compose.yml to bring up.
+	Compose []byte
+	// Project names the compose project (the -p flag), so repeated spawn
+	// requests against the same compose file don't clash.
+	Project string
+}
+
+// SpawnHostBootstrapTemplate renders the provisioning command for a spawn
+// host. Distros differ enough in shell/profile conventions (bash .profile
+// on most Linux, zsh .zprofile on macOS, PowerShell on Windows) that a
+// single hardcoded one-liner can't serve all of them correctly.
+type SpawnHostBootstrapTemplate interface {
+	Render(homeDir string, data SpawnHostBootstrapData) (string, error)
+	// DockerComposeUpCommand renders the command that installs (if needed)
+	// and brings up data.Compose on the host. Implementations that can't
+	// support this (e.g. Windows) return a clear error instead of a broken
+	// or wrong-shell command.
+	DockerComposeUpCommand(homeDir string, data DockerComposeData) (string, error)
+}
+
+var spawnHostBootstrapTemplates = map[string]SpawnHostBootstrapTemplate{
+	"linux_amd64":   linuxBashBootstrapTemplate{},
+	"linux_arm64":   linuxBashBootstrapTemplate{},
+	"darwin_amd64":  darwinZshBootstrapTemplate{},
+	"windows_amd64": windowsPowerShellBootstrapTemplate{},
+}
+
+// RegisterSpawnHostBootstrapTemplate lets admins register a custom
+// bootstrap template for a distro arch (e.g. "linux_ppc64le") without
+// patching this package.
+func RegisterSpawnHostBootstrapTemplate(archKey string, tmpl SpawnHostBootstrapTemplate) {
+	spawnHostBootstrapTemplates[archKey] = tmpl
+}
+
+func spawnHostBootstrapTemplateFor(archKey string) (SpawnHostBootstrapTemplate, error) {
+	tmpl, ok := spawnHostBootstrapTemplates[archKey]
+	if !ok {
+		return nil, errors.Errorf("no spawn host bootstrap template registered for arch '%s'", archKey)
+	}
+	return tmpl, nil
+}
+
+func evergreenYML(data SpawnHostBootstrapData) (string, error) {
+	out, err := json.Marshal(struct {
+		APIKey        string `json:"api_key"`
+		APIServerHost string `json:"api_server_host"`
+		UIServerHost  string `json:"ui_server_host"`
+		User          string `json:"user"`
+	}{
+		APIKey:        data.APIKey,
+		APIServerHost: data.APIServerHost,
+		UIServerHost:  data.UIServerHost,
+		User:          data.User,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "problem marshalling .evergreen.yml")
+	}
+	return string(out), nil
+}
+
+func fetchCommand(home, cliBinDir string, data SpawnHostBootstrapData) string {
+	if !data.FetchArtifacts || data.TaskID == "" {
+		return ""
+	}
+	return fmt.Sprintf(" && %s/evergreen -c %s/.evergreen.yml fetch -t %s --source --artifacts --dir='%s'",
+		home, cliBinDir, data.TaskID, data.WorkDir)
+}
+
+const (
+	composeHTTPTimeoutSecs   = 600
+	dockerClientTimeoutSecs  = 600
+	dockerComposeFileName    = "docker-compose.yml"
+	dockerComposeDefaultName = "evergreen-spawn-host"
+)
+
+// composeUpCommand renders the shell-agnostic tail end of bringing up a
+// compose project: writing the base64-decoded file and running
+// docker-compose against it. The file is base64-encoded in transit so
+// arbitrary YAML (quotes, heredoc-breaking sequences, etc.) round-trips
+// safely through the single-quoted shell command.
+func composeUpCommand(home string, data DockerComposeData) string {
+	project := data.Project
+	if project == "" {
+		project = dockerComposeDefaultName
+	}
+
+	composePath := fmt.Sprintf("%s/%s", home, dockerComposeFileName)
+	encoded := base64.StdEncoding.EncodeToString(data.Compose)
+
+	return fmt.Sprintf(
+		"echo '%s' | base64 -d > %s && "+
+			"export COMPOSE_HTTP_TIMEOUT=%d DOCKER_CLIENT_TIMEOUT=%d && "+
+			"docker-compose -p %s -f %s up -d",
+		encoded, composePath, composeHTTPTimeoutSecs, dockerClientTimeoutSecs, project, composePath)
+}
+
+func sortedEnv(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// linuxBashBootstrapTemplate covers both Linux amd64 and arm64: the
+// provisioning command doesn't reference the architecture at all, only the
+// home directory layout.
+type linuxBashBootstrapTemplate struct{}
+
+func (linuxBashBootstrapTemplate) Render(home string, data SpawnHostBootstrapData) (string, error) {
+	cliBinDir := fmt.Sprintf("%s/cli_bin", home)
+	evgYML, err := evergreenYML(data)
+	if err != nil {
+		return "", err
+	}
+
+	var envLines []string
+	for _, k := range sortedEnv(data.ExtraEnv) {
+		envLines = append(envLines, fmt.Sprintf("echo 'export %s=%s' >> %s/.profile || true; ", k, data.ExtraEnv[k], home))
+	}
+
+	cmd := fmt.Sprintf("mkdir -m 777 -p %s && echo '%s' > %s/.evergreen.yml && cp %s/evergreen %s && "+
+		"(%secho 'PATH=${PATH}:%s' >> %s/.profile || true; echo 'PATH=${PATH}:%s' >> %s/.bash_profile || true)",
+		cliBinDir, evgYML, cliBinDir, home, cliBinDir, strings.Join(envLines, ""), cliBinDir, home, cliBinDir, home)
+
+	return cmd + fetchCommand(home, cliBinDir, data), nil
+}
+
+// DockerComposeUpCommand installs Docker and docker-compose if they're not
+// already on PATH, then brings the compose project up in the background.
+func (linuxBashBootstrapTemplate) DockerComposeUpCommand(home string, data DockerComposeData) (string, error) {
+	installCmd := "(command -v docker >/dev/null 2>&1 || curl -fsSL https://get.docker.com | sh) && " +
+		"(command -v docker-compose >/dev/null 2>&1 || sudo curl -fsSL -o /usr/local/bin/docker-compose " +
+		"\"https://github.com/docker/compose/releases/latest/download/docker-compose-linux-$(uname -m)\" && sudo chmod +x /usr/local/bin/docker-compose)"
+
+	return installCmd + " && " + composeUpCommand(home, data), nil
+}
+
+// darwinZshBootstrapTemplate covers macOS spawn hosts, whose default shell
+// (zsh) reads .zprofile rather than .profile/.bash_profile.
+type darwinZshBootstrapTemplate struct{}
+
+func (darwinZshBootstrapTemplate) Render(home string, data SpawnHostBootstrapData) (string, error) {
+	cliBinDir := fmt.Sprintf("%s/cli_bin", home)
+	evgYML, err := evergreenYML(data)
+	if err != nil {
+		return "", err
+	}
+
+	var envLines []string
+	for _, k := range sortedEnv(data.ExtraEnv) {
+		envLines = append(envLines, fmt.Sprintf("echo 'export %s=%s' >> %s/.zprofile || true; ", k, data.ExtraEnv[k], home))
+	}
+
+	cmd := fmt.Sprintf("mkdir -m 777 -p %s && echo '%s' > %s/.evergreen.yml && cp %s/evergreen %s && "+
+		"(%secho 'PATH=${PATH}:%s' >> %s/.zprofile || true)",
+		cliBinDir, evgYML, cliBinDir, home, cliBinDir, strings.Join(envLines, ""), cliBinDir, home)
+
+	return cmd + fetchCommand(home, cliBinDir, data), nil
+}
+
+// DockerComposeUpCommand assumes Docker Desktop is already installed (the
+// normal state of macOS spawn hosts) rather than attempting an unattended
+// install, and fails loudly up front if it isn't, instead of failing deep
+// inside the compose invocation.
+func (darwinZshBootstrapTemplate) DockerComposeUpCommand(home string, data DockerComposeData) (string, error) {
+	checkCmd := "command -v docker-compose >/dev/null 2>&1 || " +
+		"{ echo 'docker-compose not found; install Docker Desktop on this host before spawning with DockerCompose set' >&2; exit 1; }"
+
+	return checkCmd + " && " + composeUpCommand(home, data), nil
+}
+
+// windowsPowerShellBootstrapTemplate covers Windows spawn hosts, which get
+// a PowerShell profile update instead of a POSIX one.
+type windowsPowerShellBootstrapTemplate struct{}
+
+func (windowsPowerShellBootstrapTemplate) Render(home string, data SpawnHostBootstrapData) (string, error) {
+	cliBinDir := fmt.Sprintf("%s/cli_bin", home)
+	evgYML, err := evergreenYML(data)
+	if err != nil {
+		return "", err
+	}
+
+	var envLines []string
+	for _, k := range sortedEnv(data.ExtraEnv) {
+		envLines = append(envLines, fmt.Sprintf("[Environment]::SetEnvironmentVariable('%s', '%s', 'User'); ", k, data.ExtraEnv[k]))
+	}
+
+	cmd := fmt.Sprintf("New-Item -ItemType Directory -Force -Path %s; Set-Content -Path %s/.evergreen.yml -Value '%s'; "+
+		"Copy-Item %s/evergreen.exe %s; %s[Environment]::SetEnvironmentVariable('PATH', \"$env:PATH;%s\", 'User')",
+		cliBinDir, cliBinDir, evgYML, home, cliBinDir, strings.Join(envLines, ""), cliBinDir)
+
+	if data.FetchArtifacts && data.TaskID != "" {
+		cmd += fmt.Sprintf("; & %s/evergreen.exe -c %s/.evergreen.yml fetch -t %s --source --artifacts --dir='%s'",
+			home, cliBinDir, data.TaskID, data.WorkDir)
+	}
+
+	return cmd, nil
+}
+
+// DockerComposeUpCommand is not supported on Windows: there's no
+// unattended, non-interactive Docker Desktop install path, so spawning a
+// Windows host with DockerCompose set fails clearly up front instead of
+// silently running a broken bash command on PowerShell.
+func (windowsPowerShellBootstrapTemplate) DockerComposeUpCommand(home string, data DockerComposeData) (string, error) {
+	return "", errors.New("docker-compose spawn host setup is not supported on Windows")
+}
@@ -0,0 +1,89 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpawnHostBootstrapTemplates(t *testing.T) {
+	data := SpawnHostBootstrapData{
+		APIKey:        "key",
+		APIServerHost: "www.example0.com/api",
+		UIServerHost:  "www.example1.com",
+		User:          "user",
+		WorkDir:       "/dir",
+	}
+
+	for testName, testCase := range map[string]struct {
+		tmpl     SpawnHostBootstrapTemplate
+		home     string
+		contains []string
+	}{
+		"LinuxBashUsesProfileAndBashProfile": {
+			tmpl: linuxBashBootstrapTemplate{},
+			home: "/home/user",
+			contains: []string{
+				"mkdir -m 777 -p /home/user/cli_bin",
+				"cp /home/user/evergreen /home/user/cli_bin",
+				"/home/user/.profile",
+				"/home/user/.bash_profile",
+			},
+		},
+		"DarwinZshUsesZprofileOnly": {
+			tmpl: darwinZshBootstrapTemplate{},
+			home: "/Users/user",
+			contains: []string{
+				"mkdir -m 777 -p /Users/user/cli_bin",
+				"cp /Users/user/evergreen /Users/user/cli_bin",
+				"/Users/user/.zprofile",
+			},
+		},
+		"WindowsPowerShellUsesSetEnvironmentVariable": {
+			tmpl: windowsPowerShellBootstrapTemplate{},
+			home: `C:\Users\user`,
+			contains: []string{
+				`New-Item -ItemType Directory -Force -Path C:\Users\user/cli_bin`,
+				`Copy-Item C:\Users\user/evergreen.exe`,
+				`[Environment]::SetEnvironmentVariable('PATH'`,
+			},
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			cmd, err := testCase.tmpl.Render(testCase.home, data)
+			require.NoError(t, err)
+			for _, s := range testCase.contains {
+				assert.Contains(t, cmd, s)
+			}
+			assert.NotContains(t, cmd, "fetch", "no task ID was set, so no fetch command should be appended")
+		})
+
+		t.Run(testName+"WithTaskFetchesArtifacts", func(t *testing.T) {
+			withTask := data
+			withTask.TaskID = "task_id"
+			withTask.FetchArtifacts = true
+
+			cmd, err := testCase.tmpl.Render(testCase.home, withTask)
+			require.NoError(t, err)
+			assert.Contains(t, cmd, "task_id")
+			assert.Contains(t, cmd, "fetch")
+		})
+	}
+}
+
+func TestSpawnHostBootstrapTemplateForUnknownArch(t *testing.T) {
+	_, err := spawnHostBootstrapTemplateFor("plan9_amd64")
+	assert.Error(t, err)
+}
+
+func TestRegisterSpawnHostBootstrapTemplate(t *testing.T) {
+	const archKey = "test_custom_arch"
+	defer delete(spawnHostBootstrapTemplates, archKey)
+
+	RegisterSpawnHostBootstrapTemplate(archKey, linuxBashBootstrapTemplate{})
+
+	tmpl, err := spawnHostBootstrapTemplateFor(archKey)
+	require.NoError(t, err)
+	assert.IsType(t, linuxBashBootstrapTemplate{}, tmpl)
+}